@@ -0,0 +1,126 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/example/janusgraph-operator/api/v1beta1"
+)
+
+// The structs shared by both versions have the same fields, so most of them are converted
+// with a plain type conversion. Only the structs holding other structs are copied field by field.
+
+// ConvertTo converts this Janusgraph to the Hub version (v1beta1)
+func (src *Janusgraph) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1beta1.Janusgraph)
+	dst.ObjectMeta = src.ObjectMeta
+
+	dst.Spec.Replicas = src.Spec.Size
+	dst.Spec.Image = v1beta1.JanusgraphImageSpec{
+		Repository: src.Spec.Image,
+		Version:    src.Spec.Version,
+	}
+	dst.Spec.Service = v1beta1.JanusgraphServiceSpec(src.Spec.Service)
+	dst.Spec.Probes = v1beta1.JanusgraphProbesSpec(src.Spec.Probes)
+	dst.Spec.Auth = (*v1beta1.JanusgraphAuthSpec)(src.Spec.Auth)
+	dst.Spec.TLS = (*v1beta1.JanusgraphTLSSpec)(src.Spec.TLS)
+	dst.Spec.Storage = (*v1beta1.JanusgraphStorageSpec)(src.Spec.Storage)
+	dst.Spec.Resources = src.Spec.Resources
+	dst.Spec.JVM = v1beta1.JanusgraphJVMSpec(src.Spec.JVM)
+	dst.Spec.Metrics = nil
+	if metrics := src.Spec.Metrics; metrics != nil {
+		dst.Spec.Metrics = &v1beta1.JanusgraphMetricsSpec{
+			Port:           metrics.Port,
+			ExporterImage:  metrics.ExporterImage,
+			ServiceMonitor: (*v1beta1.ServiceMonitorSpec)(metrics.ServiceMonitor),
+		}
+	}
+	dst.Spec.ScaleDown = v1beta1.JanusgraphScaleDownSpec(src.Spec.ScaleDown)
+	dst.Spec.Cleanup = v1beta1.JanusgraphCleanupSpec{
+		VolumeClaimPolicy: src.Spec.Cleanup.VolumeClaimPolicy,
+		TimeoutSeconds:    src.Spec.Cleanup.TimeoutSeconds,
+	}
+	if storage := src.Spec.Cleanup.FinalBackup; storage != nil {
+		dst.Spec.Cleanup.FinalBackup = &v1beta1.BackupStorage{
+			PersistentVolumeClaim: (*v1beta1.PersistentVolumeClaimStorage)(storage.PersistentVolumeClaim),
+			S3:                    (*v1beta1.S3Storage)(storage.S3),
+		}
+	}
+
+	dst.Status = v1beta1.JanusgraphStatus{
+		Nodes:              src.Status.Nodes,
+		ReadyReplicas:      src.Status.ReadyReplicas,
+		ObservedGeneration: src.Status.ObservedGeneration,
+		Version:            src.Status.Version,
+		Endpoint:           src.Status.Endpoint,
+		Conditions:         src.Status.Conditions,
+		Warnings:           src.Status.Warnings,
+		ScaleDown:          (*v1beta1.JanusgraphScaleDownStatus)(src.Status.ScaleDown),
+		Cleanup:            (*v1beta1.JanusgraphCleanupStatus)(src.Status.Cleanup),
+	}
+	return nil
+}
+
+// ConvertFrom converts from the Hub version (v1beta1) to this version
+func (dst *Janusgraph) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1beta1.Janusgraph)
+	dst.ObjectMeta = src.ObjectMeta
+
+	dst.Spec.Size = src.Spec.Replicas
+	dst.Spec.Image = src.Spec.Image.Repository
+	dst.Spec.Version = src.Spec.Image.Version
+	dst.Spec.Service = JanusgraphServiceSpec(src.Spec.Service)
+	dst.Spec.Probes = JanusgraphProbesSpec(src.Spec.Probes)
+	dst.Spec.Auth = (*JanusgraphAuthSpec)(src.Spec.Auth)
+	dst.Spec.TLS = (*JanusgraphTLSSpec)(src.Spec.TLS)
+	dst.Spec.Storage = (*JanusgraphStorageSpec)(src.Spec.Storage)
+	dst.Spec.Resources = src.Spec.Resources
+	dst.Spec.JVM = JanusgraphJVMSpec(src.Spec.JVM)
+	dst.Spec.Metrics = nil
+	if metrics := src.Spec.Metrics; metrics != nil {
+		dst.Spec.Metrics = &JanusgraphMetricsSpec{
+			Port:           metrics.Port,
+			ExporterImage:  metrics.ExporterImage,
+			ServiceMonitor: (*ServiceMonitorSpec)(metrics.ServiceMonitor),
+		}
+	}
+	dst.Spec.ScaleDown = JanusgraphScaleDownSpec(src.Spec.ScaleDown)
+	dst.Spec.Cleanup = JanusgraphCleanupSpec{
+		VolumeClaimPolicy: src.Spec.Cleanup.VolumeClaimPolicy,
+		TimeoutSeconds:    src.Spec.Cleanup.TimeoutSeconds,
+	}
+	if storage := src.Spec.Cleanup.FinalBackup; storage != nil {
+		dst.Spec.Cleanup.FinalBackup = &BackupStorage{
+			PersistentVolumeClaim: (*PersistentVolumeClaimStorage)(storage.PersistentVolumeClaim),
+			S3:                    (*S3Storage)(storage.S3),
+		}
+	}
+
+	dst.Status = JanusgraphStatus{
+		Nodes:              src.Status.Nodes,
+		ReadyReplicas:      src.Status.ReadyReplicas,
+		ObservedGeneration: src.Status.ObservedGeneration,
+		Version:            src.Status.Version,
+		Endpoint:           src.Status.Endpoint,
+		Conditions:         src.Status.Conditions,
+		Warnings:           src.Status.Warnings,
+		ScaleDown:          (*JanusgraphScaleDownStatus)(src.Status.ScaleDown),
+		Cleanup:            (*JanusgraphCleanupStatus)(src.Status.Cleanup),
+	}
+	return nil
+}
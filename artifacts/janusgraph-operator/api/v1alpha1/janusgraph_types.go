@@ -0,0 +1,388 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+// JanusgraphSpec defines the desired state of Janusgraph
+type JanusgraphSpec struct {
+	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// Size is the number of JanusGraph pods. Defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Size int32 `json:"size,omitempty"`
+
+	// Version is the JanusGraph version, used as the tag of the image. Defaults to latest.
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// Image is the repository of the JanusGraph image, tagged with Version. Defaults to horeaporutiu/janusgraph.
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Service configures the Service that exposes the Gremlin Server to clients
+	// +optional
+	Service JanusgraphServiceSpec `json:"service,omitempty"`
+
+	// Probes configures the readiness, liveness and startup probes that query the Gremlin Server
+	// +optional
+	Probes JanusgraphProbesSpec `json:"probes,omitempty"`
+
+	// Auth requires Gremlin clients to authenticate. Without it anyone who can reach the Service can
+	// read and write the graph.
+	// +optional
+	Auth *JanusgraphAuthSpec `json:"auth,omitempty"`
+
+	// TLS encrypts the Gremlin Server port with a keystore read from a Secret
+	// +optional
+	TLS *JanusgraphTLSSpec `json:"tls,omitempty"`
+
+	// Storage gives every JanusGraph pod a PersistentVolumeClaim for its BerkeleyDB and Lucene data.
+	// Without it the data is lost when a pod restarts. Storage can only be set when the Janusgraph
+	// is created, afterwards only its size may grow.
+	// +optional
+	Storage *JanusgraphStorageSpec `json:"storage,omitempty"`
+
+	// Resources are the compute resources of the JanusGraph container
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// JVM configures the heap and options of the JanusGraph JVM
+	// +optional
+	JVM JanusgraphJVMSpec `json:"jvm,omitempty"`
+
+	// Metrics exposes Gremlin Server and JVM metrics to Prometheus
+	// +optional
+	Metrics *JanusgraphMetricsSpec `json:"metrics,omitempty"`
+
+	// ScaleDown configures how pods are drained before Size is reduced
+	// +optional
+	ScaleDown JanusgraphScaleDownSpec `json:"scaleDown,omitempty"`
+
+	// Cleanup configures what is done before a deleted Janusgraph is released
+	// +optional
+	Cleanup JanusgraphCleanupSpec `json:"cleanup,omitempty"`
+}
+
+// JanusgraphCleanupSpec defines the cleanup run by the finalizer of a Janusgraph when it is deleted.
+// The Janusgraph keeps running until the cleanup has finished or timed out.
+type JanusgraphCleanupSpec struct {
+	// FinalBackup takes a backup to the given storage before the Janusgraph is deleted. The backup is
+	// kept as a JanusgraphBackup named <name>-final-<uid>, whose status records the backup file.
+	// +optional
+	FinalBackup *BackupStorage `json:"finalBackup,omitempty"`
+
+	// VolumeClaimPolicy is Retain to keep the data volumes, so a Janusgraph created with the same name
+	// finds its data again, or Delete to delete them. Defaults to Retain.
+	// +kubebuilder:validation:Enum=Retain;Delete
+	// +optional
+	VolumeClaimPolicy string `json:"volumeClaimPolicy,omitempty"`
+
+	// TimeoutSeconds is how long the cleanup may take before the Janusgraph is released anyway.
+	// Defaults to 1800.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+}
+
+// JanusgraphScaleDownSpec defines how JanusGraph pods are drained when the Janusgraph is scaled down.
+// The pods being removed are first taken out of the Services, then the operator waits for their open
+// transactions to finish before the StatefulSet is scaled down.
+type JanusgraphScaleDownSpec struct {
+	// DrainTimeoutSeconds is how long to wait for open transactions to finish. Pods with Kerberos auth,
+	// whose transactions cannot be queried by the operator, are always drained for this long. Defaults to 300.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	DrainTimeoutSeconds *int32 `json:"drainTimeoutSeconds,omitempty"`
+}
+
+// JanusgraphMetricsSpec defines how the metrics of a Janusgraph are exposed. The Gremlin Server reports
+// its metrics, e.g. request latency and transaction counts, to JMX, and a JMX exporter sidecar serves
+// them together with the JVM metrics on a dedicated <name>-metrics Service.
+type JanusgraphMetricsSpec struct {
+	// Port is the port the JMX exporter serves metrics on. Defaults to 9404.
+	// +optional
+	Port int32 `json:"port,omitempty"`
+
+	// ExporterImage is the image of the JMX exporter sidecar. Defaults to bitnami/jmx-exporter:0.17.0.
+	// +optional
+	ExporterImage string `json:"exporterImage,omitempty"`
+
+	// ServiceMonitor creates a Prometheus Operator ServiceMonitor scraping the metrics Service
+	// +optional
+	ServiceMonitor *ServiceMonitorSpec `json:"serviceMonitor,omitempty"`
+}
+
+// ServiceMonitorSpec defines the ServiceMonitor of a Janusgraph
+type ServiceMonitorSpec struct {
+	// Interval is how often Prometheus scrapes the metrics. Defaults to 30s.
+	// +optional
+	Interval string `json:"interval,omitempty"`
+
+	// Labels are added to the ServiceMonitor so the Prometheus instance selects it
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// JanusgraphJVMSpec defines the options passed to the JanusGraph JVM through JAVA_OPTIONS.
+// Without a heap size the JVM would size its heap from the memory of the node rather than the pod.
+type JanusgraphJVMSpec struct {
+	// HeapMax is the maximum heap size, e.g. 2Gi. Defaults to HeapPercentage of the memory limit.
+	// +optional
+	HeapMax *resource.Quantity `json:"heapMax,omitempty"`
+
+	// HeapMin is the initial heap size. Defaults to HeapMax.
+	// +optional
+	HeapMin *resource.Quantity `json:"heapMin,omitempty"`
+
+	// HeapPercentage is the share of the memory limit used for the heap when HeapMax is not set.
+	// The rest is left for metaspace, thread stacks and off-heap caches. Defaults to 50.
+	// +kubebuilder:validation:Minimum=10
+	// +kubebuilder:validation:Maximum=90
+	// +optional
+	HeapPercentage int32 `json:"heapPercentage,omitempty"`
+
+	// GCOptions are the garbage collector flags. Defaults to -XX:+UseG1GC.
+	// +optional
+	GCOptions []string `json:"gcOptions,omitempty"`
+
+	// ExtraOptions are added to JAVA_OPTIONS after the heap and garbage collector flags
+	// +optional
+	ExtraOptions []string `json:"extraOptions,omitempty"`
+}
+
+// JanusgraphStorageSpec defines the PersistentVolumeClaims of the JanusGraph pods
+type JanusgraphStorageSpec struct {
+	// Size is the requested size of each volume. Increasing it expands the existing volumes,
+	// which requires a StorageClass that allows volume expansion.
+	Size resource.Quantity `json:"size"`
+
+	// StorageClassName is the StorageClass of the volumes. The default StorageClass is used when empty.
+	// +optional
+	StorageClassName *string `json:"storageClassName,omitempty"`
+
+	// AccessModes are the access modes of the volumes. Defaults to ReadWriteOnce.
+	// +optional
+	AccessModes []corev1.PersistentVolumeAccessMode `json:"accessModes,omitempty"`
+}
+
+// JanusgraphServiceSpec defines how the Gremlin Server of a Janusgraph is exposed
+type JanusgraphServiceSpec struct {
+	// Type is the type of the Service, one of ClusterIP, NodePort or LoadBalancer. Defaults to ClusterIP.
+	// +kubebuilder:validation:Enum=ClusterIP;NodePort;LoadBalancer
+	// +optional
+	Type corev1.ServiceType `json:"type,omitempty"`
+
+	// Port is the port the Service listens on for Gremlin clients. Defaults to 8182.
+	// +optional
+	Port int32 `json:"port,omitempty"`
+
+	// NodePort is the port opened on every node when Type is NodePort or LoadBalancer.
+	// When left empty the cluster assigns a free port, so several Janusgraph instances can coexist.
+	// +optional
+	NodePort int32 `json:"nodePort,omitempty"`
+
+	// Annotations are added to the Service, e.g. to configure a cloud provider load balancer
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// LoadBalancerSourceRanges restricts the client IP ranges allowed through a LoadBalancer Service
+	// +optional
+	LoadBalancerSourceRanges []string `json:"loadBalancerSourceRanges,omitempty"`
+}
+
+// JanusgraphProbesSpec defines how JanusGraph pods are health checked.
+// The probes submit a trivial Gremlin query over HTTP on port 8182, so a pod only becomes ready
+// once the Gremlin Server has opened the graph.
+type JanusgraphProbesSpec struct {
+	// Query is the Gremlin query submitted by the probes. Defaults to "g.inject(1)".
+	// +optional
+	Query string `json:"query,omitempty"`
+
+	// StartupTimeoutSeconds is how long the Gremlin Server may take to open the graph
+	// before the container is restarted. Defaults to 300.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	StartupTimeoutSeconds int32 `json:"startupTimeoutSeconds,omitempty"`
+
+	// TimeoutSeconds is how long a single probe query may take. Defaults to 5.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+
+	// PeriodSeconds is how often the probes run. Defaults to 10.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	PeriodSeconds int32 `json:"periodSeconds,omitempty"`
+
+	// FailureThreshold is how many consecutive failed queries mark a pod unready,
+	// or restart it for the liveness probe. Defaults to 3.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	FailureThreshold int32 `json:"failureThreshold,omitempty"`
+}
+
+// JanusgraphAuthSpec defines how Gremlin clients authenticate to the Gremlin Server.
+// The operator's own Jobs, e.g. schema or backup Jobs, authenticate with the same credentials.
+type JanusgraphAuthSpec struct {
+	// Type is Simple for username and password authentication over SASL PLAIN and HTTP basic auth,
+	// or Kerberos for SASL GSSAPI. With Simple auth the probes run curl in the JanusGraph container,
+	// with Kerberos auth they only check that the Gremlin Server port is open.
+	// +kubebuilder:validation:Enum=Simple;Kerberos
+	Type string `json:"type"`
+
+	// CredentialsSecret is the name of a Secret holding the username and password keys. Required for Simple.
+	// +optional
+	CredentialsSecret string `json:"credentialsSecret,omitempty"`
+
+	// KerberosSecret is the name of a Secret holding the keytab of Principal under the key keytab
+	// and the Kerberos configuration under the key krb5.conf. Required for Kerberos.
+	// +optional
+	KerberosSecret string `json:"kerberosSecret,omitempty"`
+
+	// Principal is the Kerberos service principal of the Gremlin Server,
+	// e.g. gremlin/janusgraph-sample-service.default.svc@EXAMPLE.COM. Required for Kerberos.
+	// +optional
+	Principal string `json:"principal,omitempty"`
+}
+
+// JanusgraphTLSSpec defines the keystore the Gremlin Server uses for TLS
+type JanusgraphTLSSpec struct {
+	// KeystoreSecret is the name of a Secret holding the keystore under the key keystore
+	// and its password under the key password
+	KeystoreSecret string `json:"keystoreSecret"`
+
+	// KeystoreType is the format of the keystore, JKS or PKCS12. Defaults to PKCS12.
+	// +kubebuilder:validation:Enum=JKS;PKCS12
+	// +optional
+	KeystoreType string `json:"keystoreType,omitempty"`
+}
+
+// JanusgraphStatus defines the observed state of Janusgraph
+type JanusgraphStatus struct {
+	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// Nodes are the names of the JanusGraph pods that are ready to serve Gremlin queries
+	Nodes []string `json:"nodes"`
+
+	// ReadyReplicas is the number of JanusGraph pods that are ready to serve Gremlin queries
+	// +optional
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+
+	// ObservedGeneration is the generation of the spec the status was computed from
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Version is the JanusGraph version every pod runs. It changes once a rolling upgrade has completed.
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// Endpoint is the Gremlin endpoint of the Janusgraph, e.g. ws://203.0.113.10:8182/gremlin.
+	// It is the load balancer ingress of a LoadBalancer Service, otherwise the in-cluster Service address.
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// Conditions are the StorageReady, ServiceReady, GremlinReady and Upgrading conditions of the Janusgraph
+	// +optional
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+
+	// Warnings are problems with the spec that do not stop the reconcile,
+	// e.g. a LoadBalancer Service exposing a Gremlin Server without auth
+	// +optional
+	Warnings []string `json:"warnings,omitempty"`
+
+	// ScaleDown is the scale down in progress, if any
+	// +optional
+	ScaleDown *JanusgraphScaleDownStatus `json:"scaleDown,omitempty"`
+
+	// Cleanup is the progress of the cleanup of a deleted Janusgraph
+	// +optional
+	Cleanup *JanusgraphCleanupStatus `json:"cleanup,omitempty"`
+}
+
+// JanusgraphCleanupStatus defines the state of the cleanup of a deleted Janusgraph
+type JanusgraphCleanupStatus struct {
+	// Phase is Running while the cleanup is in progress, Failed when a step failed and the cleanup
+	// waits for the timeout, or TimedOut when the Janusgraph was released before the cleanup finished
+	Phase string `json:"phase"`
+
+	// Message explains the phase, e.g. the step the cleanup is waiting for
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// JanusgraphScaleDownStatus defines the state of a scale down in progress
+type JanusgraphScaleDownStatus struct {
+	// Replicas is the number of replicas the StatefulSet is scaled down to
+	Replicas int32 `json:"replicas"`
+
+	// Pods are the pods being drained
+	Pods []string `json:"pods"`
+
+	// StartTime is when the pods were taken out of the Services
+	StartTime metav1.Time `json:"startTime"`
+
+	// OpenTransactions is the number of transactions last seen open on the pods being drained
+	// +optional
+	OpenTransactions int32 `json:"openTransactions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Size",type=integer,JSONPath=`.spec.size`
+// +kubebuilder:printcolumn:name="Ready",type=integer,JSONPath=`.status.readyReplicas`
+// +kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.status.version`
+// +kubebuilder:printcolumn:name="Endpoint",type=string,JSONPath=`.status.endpoint`
+// +kubebuilder:printcolumn:name="Upgrading",type=string,JSONPath=`.status.conditions[?(@.type=="Upgrading")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// Janusgraph is the Schema for the janusgraphs API
+type Janusgraph struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   JanusgraphSpec   `json:"spec,omitempty"`
+	Status JanusgraphStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// JanusgraphList contains a list of Janusgraph
+type JanusgraphList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Janusgraph `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Janusgraph{}, &JanusgraphList{})
+}
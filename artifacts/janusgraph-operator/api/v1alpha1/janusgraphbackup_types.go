@@ -0,0 +1,144 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// JanusgraphBackupSpec defines how and where a Janusgraph is backed up.
+// The graph is exported to a GraphSON file named after the backup and the time it was taken.
+type JanusgraphBackupSpec struct {
+	// JanusgraphRef is the name of the Janusgraph in the same namespace to back up
+	JanusgraphRef string `json:"janusgraphRef"`
+
+	// Schedule is a cron expression, e.g. "0 2 * * *". When empty the backup is taken once.
+	// +optional
+	Schedule string `json:"schedule,omitempty"`
+
+	// Storage is where the backup files are written
+	Storage BackupStorage `json:"storage"`
+
+	// TimeoutSeconds is how long exporting the graph may take. Defaults to 1800.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+}
+
+// BackupStorage selects where backup files are kept. Exactly one of its fields must be set.
+type BackupStorage struct {
+	// PersistentVolumeClaim keeps the backup files on a PersistentVolumeClaim in the same namespace
+	// +optional
+	PersistentVolumeClaim *PersistentVolumeClaimStorage `json:"persistentVolumeClaim,omitempty"`
+
+	// S3 keeps the backup files in a bucket of an S3 compatible object store such as MinIO
+	// +optional
+	S3 *S3Storage `json:"s3,omitempty"`
+}
+
+// PersistentVolumeClaimStorage is a directory on a PersistentVolumeClaim
+type PersistentVolumeClaimStorage struct {
+	// ClaimName is the name of the PersistentVolumeClaim
+	ClaimName string `json:"claimName"`
+
+	// Path is the directory of the backup files relative to the root of the volume
+	// +optional
+	Path string `json:"path,omitempty"`
+}
+
+// S3Storage is a location in an S3 compatible object store
+type S3Storage struct {
+	// Endpoint is the URL of the object store, e.g. https://s3.amazonaws.com or http://minio:9000
+	Endpoint string `json:"endpoint"`
+
+	// Bucket is the name of the bucket
+	Bucket string `json:"bucket"`
+
+	// Prefix is prepended to the names of the backup files, e.g. "janusgraph/"
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+
+	// CredentialsSecret is the name of a Secret in the same namespace holding the
+	// accessKeyID and secretAccessKey keys
+	CredentialsSecret string `json:"credentialsSecret"`
+
+	// Insecure skips verification of the TLS certificate of the object store
+	// +optional
+	Insecure bool `json:"insecure,omitempty"`
+}
+
+// JanusgraphBackupStatus defines the observed state of JanusgraphBackup
+type JanusgraphBackupStatus struct {
+	// Phase is one of Pending, Running, Scheduled, Succeeded or Failed
+	// +optional
+	Phase string `json:"phase,omitempty"`
+
+	// Message explains the current phase, e.g. the error that stopped the last backup
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// LastBackup describes the most recent successful backup
+	// +optional
+	LastBackup *BackupRecord `json:"lastBackup,omitempty"`
+}
+
+// BackupRecord describes a backup file
+type BackupRecord struct {
+	// File is the name of the backup file, to be used by a JanusgraphRestore
+	File string `json:"file"`
+
+	// Location is the full location of the backup file, e.g. s3://backups/janusgraph/graph-20210601-020000.json
+	Location string `json:"location"`
+
+	// Time is when the backup finished
+	Time metav1.Time `json:"time"`
+
+	// Vertices is the number of vertices in the backup
+	Vertices int64 `json:"vertices"`
+
+	// Edges is the number of edges in the backup
+	Edges int64 `json:"edges"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Janusgraph",type=string,JSONPath=`.spec.janusgraphRef`
+// +kubebuilder:printcolumn:name="Schedule",type=string,JSONPath=`.spec.schedule`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Last Backup",type=string,JSONPath=`.status.lastBackup.file`
+
+// JanusgraphBackup is the Schema for the janusgraphbackups API
+type JanusgraphBackup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   JanusgraphBackupSpec   `json:"spec,omitempty"`
+	Status JanusgraphBackupStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// JanusgraphBackupList contains a list of JanusgraphBackup
+type JanusgraphBackupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []JanusgraphBackup `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&JanusgraphBackup{}, &JanusgraphBackupList{})
+}
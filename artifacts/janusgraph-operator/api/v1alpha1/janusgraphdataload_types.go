@@ -0,0 +1,126 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// JanusgraphDataLoadSpec defines the data to load into a Janusgraph.
+// A data load runs once; create a new JanusgraphDataLoad to load the data again.
+type JanusgraphDataLoadSpec struct {
+	// JanusgraphRef is the name of the Janusgraph in the same namespace the data is loaded into
+	JanusgraphRef string `json:"janusgraphRef"`
+
+	// Format is the format of the data. Groovy scripts are run on the Gremlin Server with graph and g bound,
+	// GraphSON, GraphML and CSV files are parsed by the loader and written to the graph in batches.
+	// +kubebuilder:validation:Enum=Groovy;GraphSON;GraphML;CSV
+	Format string `json:"format"`
+
+	// Source is where the script or data file is read from
+	Source DataLoadSource `json:"source"`
+
+	// CSVVertexLabel is the vertex label given to every row of a CSV file. The first row of the
+	// file names the property each column is stored in. Fields may be quoted as in RFC 4180 to hold
+	// commas or line breaks, and a row may not have more fields than the first. Defaults to "vertex".
+	// +optional
+	CSVVertexLabel string `json:"csvVertexLabel,omitempty"`
+
+	// BatchSize is the number of vertices or edges written per request. Defaults to 500.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	BatchSize int32 `json:"batchSize,omitempty"`
+
+	// TimeoutSeconds is how long a single request to the Gremlin Server may take. Defaults to 600.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+}
+
+// DataLoadSource selects the file holding the data. Exactly one of its fields must be set.
+type DataLoadSource struct {
+	// ConfigMap selects a key of a ConfigMap in the same namespace
+	// +optional
+	ConfigMap *corev1.ConfigMapKeySelector `json:"configMap,omitempty"`
+
+	// PersistentVolumeClaim selects a file on a PersistentVolumeClaim in the same namespace
+	// +optional
+	PersistentVolumeClaim *PersistentVolumeClaimFile `json:"persistentVolumeClaim,omitempty"`
+}
+
+// PersistentVolumeClaimFile selects a file on a PersistentVolumeClaim
+type PersistentVolumeClaimFile struct {
+	// ClaimName is the name of the PersistentVolumeClaim
+	ClaimName string `json:"claimName"`
+
+	// Path is the path of the file relative to the root of the volume
+	Path string `json:"path"`
+}
+
+// JanusgraphDataLoadStatus defines the observed state of JanusgraphDataLoad
+type JanusgraphDataLoadStatus struct {
+	// Phase is one of Pending, Running, Succeeded or Failed
+	// +optional
+	Phase string `json:"phase,omitempty"`
+
+	// Vertices is the number of vertices added to the graph
+	// +optional
+	Vertices int64 `json:"vertices,omitempty"`
+
+	// Edges is the number of edges added to the graph
+	// +optional
+	Edges int64 `json:"edges,omitempty"`
+
+	// Message explains the current phase, e.g. the error that stopped the data load
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// CompletionTime is when the data load succeeded or failed
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Janusgraph",type=string,JSONPath=`.spec.janusgraphRef`
+// +kubebuilder:printcolumn:name="Format",type=string,JSONPath=`.spec.format`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Vertices",type=integer,JSONPath=`.status.vertices`
+// +kubebuilder:printcolumn:name="Edges",type=integer,JSONPath=`.status.edges`
+
+// JanusgraphDataLoad is the Schema for the janusgraphdataloads API
+type JanusgraphDataLoad struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   JanusgraphDataLoadSpec   `json:"spec,omitempty"`
+	Status JanusgraphDataLoadStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// JanusgraphDataLoadList contains a list of JanusgraphDataLoad
+type JanusgraphDataLoadList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []JanusgraphDataLoad `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&JanusgraphDataLoad{}, &JanusgraphDataLoadList{})
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// JanusgraphIndexJobSpec defines a management action on an index of a Janusgraph.
+// An index job runs once; create a new JanusgraphIndexJob to run the action again.
+type JanusgraphIndexJobSpec struct {
+	// JanusgraphRef is the name of the Janusgraph in the same namespace holding the index
+	JanusgraphRef string `json:"janusgraphRef"`
+
+	// IndexName is the name of the graph index
+	IndexName string `json:"indexName"`
+
+	// Action is the management action to run. REINDEX and ENABLE register an INSTALLED index first,
+	// REMOVE disables an index before removing it. The action cannot be changed once the job has started.
+	// +kubebuilder:validation:Enum=REINDEX;ENABLE;DISABLE;REMOVE
+	Action string `json:"action"`
+
+	// TimeoutSeconds is how long a single step of the action may take. Defaults to 3600.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+}
+
+// JanusgraphIndexJobStatus defines the observed state of JanusgraphIndexJob
+type JanusgraphIndexJobStatus struct {
+	// Phase is one of Pending, Running, Succeeded or Failed
+	// +optional
+	Phase string `json:"phase,omitempty"`
+
+	// Action is the action the job started with, a later change of Spec.Action fails the job
+	// +optional
+	Action string `json:"action,omitempty"`
+
+	// Progress is the number of completed steps out of the steps of the action, e.g. "1/2"
+	// +optional
+	Progress string `json:"progress,omitempty"`
+
+	// CompletedSteps is the number of steps of the action that have completed
+	// +optional
+	CompletedSteps int32 `json:"completedSteps,omitempty"`
+
+	// CurrentStep is the management action currently running, e.g. REGISTER_INDEX
+	// +optional
+	CurrentStep string `json:"currentStep,omitempty"`
+
+	// IndexStatus is the status of the index after the last completed step
+	// +optional
+	IndexStatus string `json:"indexStatus,omitempty"`
+
+	// RecordsAdded is the number of index records written by a reindex
+	// +optional
+	RecordsAdded int64 `json:"recordsAdded,omitempty"`
+
+	// RecordsFailed is the number of elements a reindex or removal failed on
+	// +optional
+	RecordsFailed int64 `json:"recordsFailed,omitempty"`
+
+	// Message explains the current phase, e.g. why a step failed
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// CompletionTime is when the action succeeded or failed
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Index",type=string,JSONPath=`.spec.indexName`
+// +kubebuilder:printcolumn:name="Action",type=string,JSONPath=`.spec.action`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Progress",type=string,JSONPath=`.status.progress`
+// +kubebuilder:printcolumn:name="Index Status",type=string,JSONPath=`.status.indexStatus`
+
+// JanusgraphIndexJob is the Schema for the janusgraphindexjobs API
+type JanusgraphIndexJob struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   JanusgraphIndexJobSpec   `json:"spec,omitempty"`
+	Status JanusgraphIndexJobStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// JanusgraphIndexJobList contains a list of JanusgraphIndexJob
+type JanusgraphIndexJobList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []JanusgraphIndexJob `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&JanusgraphIndexJob{}, &JanusgraphIndexJobList{})
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var janusgraphindexjoblog = logf.Log.WithName("janusgraphindexjob-resource")
+
+// SetupWebhookWithManager registers the validating webhook of JanusgraphIndexJob with the manager.
+func (r *JanusgraphIndexJob) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-graph-example-com-v1alpha1-janusgraphindexjob,mutating=false,failurePolicy=fail,sideEffects=None,groups=graph.example.com,resources=janusgraphindexjobs,verbs=update,versions=v1alpha1,name=vjanusgraphindexjob.kb.io,admissionReviewVersions={v1,v1beta1}
+
+var _ webhook.Validator = &JanusgraphIndexJob{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *JanusgraphIndexJob) ValidateCreate() error {
+	janusgraphindexjoblog.Info("validate create", "name", r.Name)
+
+	return nil
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *JanusgraphIndexJob) ValidateUpdate(old runtime.Object) error {
+	janusgraphindexjoblog.Info("validate update", "name", r.Name)
+
+	oldIndexJob, ok := old.(*JanusgraphIndexJob)
+	if !ok {
+		return nil
+	}
+	//the steps already run belong to the action the job started with
+	var allErrs field.ErrorList
+	if oldIndexJob.Status.Phase != "" && r.Spec.Action != oldIndexJob.Spec.Action {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "action"), "cannot be changed after the job has started"))
+	}
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(schema.GroupKind{Group: GroupVersion.Group, Kind: "JanusgraphIndexJob"}, r.Name, allErrs)
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *JanusgraphIndexJob) ValidateDelete() error {
+	janusgraphindexjoblog.Info("validate delete", "name", r.Name)
+
+	return nil
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// JanusgraphRestoreSpec defines which backup is restored into which Janusgraph.
+// A restore runs once and loads the vertices and edges of the backup into an empty graph.
+type JanusgraphRestoreSpec struct {
+	// JanusgraphRef is the name of the Janusgraph in the same namespace the backup is restored into
+	JanusgraphRef string `json:"janusgraphRef"`
+
+	// Janusgraph is the spec of the Janusgraph to create when JanusgraphRef does not exist yet
+	// +optional
+	Janusgraph *JanusgraphSpec `json:"janusgraph,omitempty"`
+
+	// BackupRef is the name of a JanusgraphBackup in the same namespace. Its storage is used,
+	// and its last backup unless File is set.
+	// +optional
+	BackupRef string `json:"backupRef,omitempty"`
+
+	// Storage is where the backup file is read from when BackupRef is not set
+	// +optional
+	Storage *BackupStorage `json:"storage,omitempty"`
+
+	// File is the name of the backup file to restore
+	// +optional
+	File string `json:"file,omitempty"`
+
+	// ExistingGraph is what is done when the graph already has vertices. Reject fails the restore
+	// without changing the graph, Drop removes all vertices and edges before the backup is loaded.
+	// Defaults to Reject.
+	// +kubebuilder:validation:Enum=Reject;Drop
+	// +optional
+	ExistingGraph string `json:"existingGraph,omitempty"`
+
+	// TimeoutSeconds is how long a single request to the Gremlin Server may take. Defaults to 600.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+}
+
+// JanusgraphRestoreStatus defines the observed state of JanusgraphRestore
+type JanusgraphRestoreStatus struct {
+	// Phase is one of Pending, Running, Succeeded or Failed
+	// +optional
+	Phase string `json:"phase,omitempty"`
+
+	// Message explains the current phase, e.g. the error that stopped the restore
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// File is the name of the backup file being restored
+	// +optional
+	File string `json:"file,omitempty"`
+
+	// Vertices is the number of vertices restored
+	// +optional
+	Vertices int64 `json:"vertices,omitempty"`
+
+	// Edges is the number of edges restored
+	// +optional
+	Edges int64 `json:"edges,omitempty"`
+
+	// CompletionTime is when the restore succeeded or failed
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Janusgraph",type=string,JSONPath=`.spec.janusgraphRef`
+// +kubebuilder:printcolumn:name="File",type=string,JSONPath=`.status.file`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+
+// JanusgraphRestore is the Schema for the janusgraphrestores API
+type JanusgraphRestore struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   JanusgraphRestoreSpec   `json:"spec,omitempty"`
+	Status JanusgraphRestoreStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// JanusgraphRestoreList contains a list of JanusgraphRestore
+type JanusgraphRestoreList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []JanusgraphRestore `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&JanusgraphRestore{}, &JanusgraphRestoreList{})
+}
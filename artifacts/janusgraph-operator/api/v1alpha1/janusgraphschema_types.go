@@ -0,0 +1,168 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// JanusgraphSchemaSpec defines the desired schema of a Janusgraph graph.
+// Schema elements are only ever added: existing property keys, labels and indexes are left untouched.
+type JanusgraphSchemaSpec struct {
+	// JanusgraphRef is the name of the Janusgraph in the same namespace the schema is applied to
+	JanusgraphRef string `json:"janusgraphRef"`
+
+	// PropertyKeys are the property keys of the graph
+	// +optional
+	PropertyKeys []PropertyKey `json:"propertyKeys,omitempty"`
+
+	// VertexLabels are the vertex labels of the graph
+	// +optional
+	VertexLabels []VertexLabel `json:"vertexLabels,omitempty"`
+
+	// EdgeLabels are the edge labels of the graph
+	// +optional
+	EdgeLabels []EdgeLabel `json:"edgeLabels,omitempty"`
+
+	// CompositeIndexes are indexes on exact matches of property values, kept in the storage backend
+	// +optional
+	CompositeIndexes []CompositeIndex `json:"compositeIndexes,omitempty"`
+
+	// MixedIndexes are full text, range and geo indexes kept in an indexing backend
+	// +optional
+	MixedIndexes []MixedIndex `json:"mixedIndexes,omitempty"`
+}
+
+// PropertyKey defines a JanusGraph property key
+type PropertyKey struct {
+	Name string `json:"name"`
+
+	// DataType is the Java class of the property values
+	// +kubebuilder:validation:Enum=String;Character;Boolean;Byte;Short;Integer;Long;Float;Double;Date;Geoshape;UUID
+	DataType string `json:"dataType"`
+
+	// Cardinality is how many values an element may hold for the key. Defaults to SINGLE.
+	// +kubebuilder:validation:Enum=SINGLE;LIST;SET
+	// +optional
+	Cardinality string `json:"cardinality,omitempty"`
+}
+
+// VertexLabel defines a JanusGraph vertex label
+type VertexLabel struct {
+	Name string `json:"name"`
+}
+
+// EdgeLabel defines a JanusGraph edge label
+type EdgeLabel struct {
+	Name string `json:"name"`
+
+	// Multiplicity constrains the edges with this label between a pair of vertices. Defaults to MULTI.
+	// +kubebuilder:validation:Enum=MULTI;SIMPLE;MANY2ONE;ONE2MANY;ONE2ONE
+	// +optional
+	Multiplicity string `json:"multiplicity,omitempty"`
+}
+
+// CompositeIndex defines a JanusGraph composite graph index
+type CompositeIndex struct {
+	Name string `json:"name"`
+
+	// ElementType is the kind of element indexed. Defaults to Vertex.
+	// +kubebuilder:validation:Enum=Vertex;Edge
+	// +optional
+	ElementType string `json:"elementType,omitempty"`
+
+	// Keys are the property keys covered by the index
+	// +kubebuilder:validation:MinItems=1
+	Keys []string `json:"keys"`
+
+	// Unique makes the index enforce uniqueness of the indexed values
+	// +optional
+	Unique bool `json:"unique,omitempty"`
+}
+
+// MixedIndex defines a JanusGraph mixed graph index
+type MixedIndex struct {
+	Name string `json:"name"`
+
+	// ElementType is the kind of element indexed. Defaults to Vertex.
+	// +kubebuilder:validation:Enum=Vertex;Edge
+	// +optional
+	ElementType string `json:"elementType,omitempty"`
+
+	// Keys are the property keys covered by the index
+	// +kubebuilder:validation:MinItems=1
+	Keys []string `json:"keys"`
+
+	// Backend is the name of the indexing backend configured in JanusGraph. Defaults to "search".
+	// +optional
+	Backend string `json:"backend,omitempty"`
+}
+
+// JanusgraphSchemaStatus defines the observed state of JanusgraphSchema
+type JanusgraphSchemaStatus struct {
+	// Phase is one of Pending, Applying, Applied or Failed
+	// +optional
+	Phase string `json:"phase,omitempty"`
+
+	// AppliedRevision is the metadata.generation of the schema that was last applied successfully
+	// +optional
+	AppliedRevision int64 `json:"appliedRevision,omitempty"`
+
+	// Message explains the current phase, e.g. the error returned by the management API
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Indexes reports the status of every index of the schema
+	// +optional
+	Indexes []IndexStatus `json:"indexes,omitempty"`
+}
+
+// IndexStatus reports the status of a JanusGraph index
+type IndexStatus struct {
+	Name string `json:"name"`
+
+	// Status is the JanusGraph schema status of the index: INSTALLED, REGISTERED, ENABLED or DISABLED
+	Status string `json:"status"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Janusgraph",type=string,JSONPath=`.spec.janusgraphRef`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Revision",type=integer,JSONPath=`.status.appliedRevision`
+
+// JanusgraphSchema is the Schema for the janusgraphschemas API
+type JanusgraphSchema struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   JanusgraphSchemaSpec   `json:"spec,omitempty"`
+	Status JanusgraphSchemaStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// JanusgraphSchemaList contains a list of JanusgraphSchema
+type JanusgraphSchemaList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []JanusgraphSchema `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&JanusgraphSchema{}, &JanusgraphSchemaList{})
+}
@@ -0,0 +1,1140 @@
+//go:build !ignore_autogenerated
+// +build !ignore_autogenerated
+
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by controller-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+)
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupRecord) DeepCopyInto(out *BackupRecord) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupRecord.
+func (in *BackupRecord) DeepCopy() *BackupRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupStorage) DeepCopyInto(out *BackupStorage) {
+	*out = *in
+	if in.PersistentVolumeClaim != nil {
+		in, out := &in.PersistentVolumeClaim, &out.PersistentVolumeClaim
+		*out = new(PersistentVolumeClaimStorage)
+		**out = **in
+	}
+	if in.S3 != nil {
+		in, out := &in.S3, &out.S3
+		*out = new(S3Storage)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupStorage.
+func (in *BackupStorage) DeepCopy() *BackupStorage {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupStorage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CompositeIndex) DeepCopyInto(out *CompositeIndex) {
+	*out = *in
+	if in.Keys != nil {
+		in, out := &in.Keys, &out.Keys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CompositeIndex.
+func (in *CompositeIndex) DeepCopy() *CompositeIndex {
+	if in == nil {
+		return nil
+	}
+	out := new(CompositeIndex)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DataLoadSource) DeepCopyInto(out *DataLoadSource) {
+	*out = *in
+	if in.ConfigMap != nil {
+		in, out := &in.ConfigMap, &out.ConfigMap
+		*out = new(v1.ConfigMapKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PersistentVolumeClaim != nil {
+		in, out := &in.PersistentVolumeClaim, &out.PersistentVolumeClaim
+		*out = new(PersistentVolumeClaimFile)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataLoadSource.
+func (in *DataLoadSource) DeepCopy() *DataLoadSource {
+	if in == nil {
+		return nil
+	}
+	out := new(DataLoadSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EdgeLabel) DeepCopyInto(out *EdgeLabel) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EdgeLabel.
+func (in *EdgeLabel) DeepCopy() *EdgeLabel {
+	if in == nil {
+		return nil
+	}
+	out := new(EdgeLabel)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IndexStatus) DeepCopyInto(out *IndexStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IndexStatus.
+func (in *IndexStatus) DeepCopy() *IndexStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(IndexStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Janusgraph) DeepCopyInto(out *Janusgraph) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Janusgraph.
+func (in *Janusgraph) DeepCopy() *Janusgraph {
+	if in == nil {
+		return nil
+	}
+	out := new(Janusgraph)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Janusgraph) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphAuthSpec) DeepCopyInto(out *JanusgraphAuthSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphAuthSpec.
+func (in *JanusgraphAuthSpec) DeepCopy() *JanusgraphAuthSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphAuthSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphBackup) DeepCopyInto(out *JanusgraphBackup) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphBackup.
+func (in *JanusgraphBackup) DeepCopy() *JanusgraphBackup {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphBackup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *JanusgraphBackup) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphBackupList) DeepCopyInto(out *JanusgraphBackupList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]JanusgraphBackup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphBackupList.
+func (in *JanusgraphBackupList) DeepCopy() *JanusgraphBackupList {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphBackupList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *JanusgraphBackupList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphBackupSpec) DeepCopyInto(out *JanusgraphBackupSpec) {
+	*out = *in
+	in.Storage.DeepCopyInto(&out.Storage)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphBackupSpec.
+func (in *JanusgraphBackupSpec) DeepCopy() *JanusgraphBackupSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphBackupSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphBackupStatus) DeepCopyInto(out *JanusgraphBackupStatus) {
+	*out = *in
+	if in.LastBackup != nil {
+		in, out := &in.LastBackup, &out.LastBackup
+		*out = new(BackupRecord)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphBackupStatus.
+func (in *JanusgraphBackupStatus) DeepCopy() *JanusgraphBackupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphBackupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphCleanupSpec) DeepCopyInto(out *JanusgraphCleanupSpec) {
+	*out = *in
+	if in.FinalBackup != nil {
+		in, out := &in.FinalBackup, &out.FinalBackup
+		*out = new(BackupStorage)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphCleanupSpec.
+func (in *JanusgraphCleanupSpec) DeepCopy() *JanusgraphCleanupSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphCleanupSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphCleanupStatus) DeepCopyInto(out *JanusgraphCleanupStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphCleanupStatus.
+func (in *JanusgraphCleanupStatus) DeepCopy() *JanusgraphCleanupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphCleanupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphDataLoad) DeepCopyInto(out *JanusgraphDataLoad) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphDataLoad.
+func (in *JanusgraphDataLoad) DeepCopy() *JanusgraphDataLoad {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphDataLoad)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *JanusgraphDataLoad) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphDataLoadList) DeepCopyInto(out *JanusgraphDataLoadList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]JanusgraphDataLoad, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphDataLoadList.
+func (in *JanusgraphDataLoadList) DeepCopy() *JanusgraphDataLoadList {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphDataLoadList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *JanusgraphDataLoadList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphDataLoadSpec) DeepCopyInto(out *JanusgraphDataLoadSpec) {
+	*out = *in
+	in.Source.DeepCopyInto(&out.Source)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphDataLoadSpec.
+func (in *JanusgraphDataLoadSpec) DeepCopy() *JanusgraphDataLoadSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphDataLoadSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphDataLoadStatus) DeepCopyInto(out *JanusgraphDataLoadStatus) {
+	*out = *in
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphDataLoadStatus.
+func (in *JanusgraphDataLoadStatus) DeepCopy() *JanusgraphDataLoadStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphDataLoadStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphIndexJob) DeepCopyInto(out *JanusgraphIndexJob) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphIndexJob.
+func (in *JanusgraphIndexJob) DeepCopy() *JanusgraphIndexJob {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphIndexJob)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *JanusgraphIndexJob) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphIndexJobList) DeepCopyInto(out *JanusgraphIndexJobList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]JanusgraphIndexJob, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphIndexJobList.
+func (in *JanusgraphIndexJobList) DeepCopy() *JanusgraphIndexJobList {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphIndexJobList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *JanusgraphIndexJobList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphIndexJobSpec) DeepCopyInto(out *JanusgraphIndexJobSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphIndexJobSpec.
+func (in *JanusgraphIndexJobSpec) DeepCopy() *JanusgraphIndexJobSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphIndexJobSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphIndexJobStatus) DeepCopyInto(out *JanusgraphIndexJobStatus) {
+	*out = *in
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphIndexJobStatus.
+func (in *JanusgraphIndexJobStatus) DeepCopy() *JanusgraphIndexJobStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphIndexJobStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphJVMSpec) DeepCopyInto(out *JanusgraphJVMSpec) {
+	*out = *in
+	if in.HeapMax != nil {
+		in, out := &in.HeapMax, &out.HeapMax
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.HeapMin != nil {
+		in, out := &in.HeapMin, &out.HeapMin
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.GCOptions != nil {
+		in, out := &in.GCOptions, &out.GCOptions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExtraOptions != nil {
+		in, out := &in.ExtraOptions, &out.ExtraOptions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphJVMSpec.
+func (in *JanusgraphJVMSpec) DeepCopy() *JanusgraphJVMSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphJVMSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphList) DeepCopyInto(out *JanusgraphList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Janusgraph, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphList.
+func (in *JanusgraphList) DeepCopy() *JanusgraphList {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *JanusgraphList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphMetricsSpec) DeepCopyInto(out *JanusgraphMetricsSpec) {
+	*out = *in
+	if in.ServiceMonitor != nil {
+		in, out := &in.ServiceMonitor, &out.ServiceMonitor
+		*out = new(ServiceMonitorSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphMetricsSpec.
+func (in *JanusgraphMetricsSpec) DeepCopy() *JanusgraphMetricsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphMetricsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphProbesSpec) DeepCopyInto(out *JanusgraphProbesSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphProbesSpec.
+func (in *JanusgraphProbesSpec) DeepCopy() *JanusgraphProbesSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphProbesSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphRestore) DeepCopyInto(out *JanusgraphRestore) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphRestore.
+func (in *JanusgraphRestore) DeepCopy() *JanusgraphRestore {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphRestore)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *JanusgraphRestore) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphRestoreList) DeepCopyInto(out *JanusgraphRestoreList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]JanusgraphRestore, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphRestoreList.
+func (in *JanusgraphRestoreList) DeepCopy() *JanusgraphRestoreList {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphRestoreList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *JanusgraphRestoreList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphRestoreSpec) DeepCopyInto(out *JanusgraphRestoreSpec) {
+	*out = *in
+	if in.Janusgraph != nil {
+		in, out := &in.Janusgraph, &out.Janusgraph
+		*out = new(JanusgraphSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Storage != nil {
+		in, out := &in.Storage, &out.Storage
+		*out = new(BackupStorage)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphRestoreSpec.
+func (in *JanusgraphRestoreSpec) DeepCopy() *JanusgraphRestoreSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphRestoreSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphRestoreStatus) DeepCopyInto(out *JanusgraphRestoreStatus) {
+	*out = *in
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphRestoreStatus.
+func (in *JanusgraphRestoreStatus) DeepCopy() *JanusgraphRestoreStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphRestoreStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphScaleDownSpec) DeepCopyInto(out *JanusgraphScaleDownSpec) {
+	*out = *in
+	if in.DrainTimeoutSeconds != nil {
+		in, out := &in.DrainTimeoutSeconds, &out.DrainTimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphScaleDownSpec.
+func (in *JanusgraphScaleDownSpec) DeepCopy() *JanusgraphScaleDownSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphScaleDownSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphScaleDownStatus) DeepCopyInto(out *JanusgraphScaleDownStatus) {
+	*out = *in
+	if in.Pods != nil {
+		in, out := &in.Pods, &out.Pods
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.StartTime.DeepCopyInto(&out.StartTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphScaleDownStatus.
+func (in *JanusgraphScaleDownStatus) DeepCopy() *JanusgraphScaleDownStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphScaleDownStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphSchema) DeepCopyInto(out *JanusgraphSchema) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphSchema.
+func (in *JanusgraphSchema) DeepCopy() *JanusgraphSchema {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphSchema)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *JanusgraphSchema) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphSchemaList) DeepCopyInto(out *JanusgraphSchemaList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]JanusgraphSchema, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphSchemaList.
+func (in *JanusgraphSchemaList) DeepCopy() *JanusgraphSchemaList {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphSchemaList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *JanusgraphSchemaList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphSchemaSpec) DeepCopyInto(out *JanusgraphSchemaSpec) {
+	*out = *in
+	if in.PropertyKeys != nil {
+		in, out := &in.PropertyKeys, &out.PropertyKeys
+		*out = make([]PropertyKey, len(*in))
+		copy(*out, *in)
+	}
+	if in.VertexLabels != nil {
+		in, out := &in.VertexLabels, &out.VertexLabels
+		*out = make([]VertexLabel, len(*in))
+		copy(*out, *in)
+	}
+	if in.EdgeLabels != nil {
+		in, out := &in.EdgeLabels, &out.EdgeLabels
+		*out = make([]EdgeLabel, len(*in))
+		copy(*out, *in)
+	}
+	if in.CompositeIndexes != nil {
+		in, out := &in.CompositeIndexes, &out.CompositeIndexes
+		*out = make([]CompositeIndex, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.MixedIndexes != nil {
+		in, out := &in.MixedIndexes, &out.MixedIndexes
+		*out = make([]MixedIndex, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphSchemaSpec.
+func (in *JanusgraphSchemaSpec) DeepCopy() *JanusgraphSchemaSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphSchemaSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphSchemaStatus) DeepCopyInto(out *JanusgraphSchemaStatus) {
+	*out = *in
+	if in.Indexes != nil {
+		in, out := &in.Indexes, &out.Indexes
+		*out = make([]IndexStatus, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphSchemaStatus.
+func (in *JanusgraphSchemaStatus) DeepCopy() *JanusgraphSchemaStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphSchemaStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphServiceSpec) DeepCopyInto(out *JanusgraphServiceSpec) {
+	*out = *in
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.LoadBalancerSourceRanges != nil {
+		in, out := &in.LoadBalancerSourceRanges, &out.LoadBalancerSourceRanges
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphServiceSpec.
+func (in *JanusgraphServiceSpec) DeepCopy() *JanusgraphServiceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphServiceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphSpec) DeepCopyInto(out *JanusgraphSpec) {
+	*out = *in
+	in.Service.DeepCopyInto(&out.Service)
+	out.Probes = in.Probes
+	if in.Auth != nil {
+		in, out := &in.Auth, &out.Auth
+		*out = new(JanusgraphAuthSpec)
+		**out = **in
+	}
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(JanusgraphTLSSpec)
+		**out = **in
+	}
+	if in.Storage != nil {
+		in, out := &in.Storage, &out.Storage
+		*out = new(JanusgraphStorageSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	in.Resources.DeepCopyInto(&out.Resources)
+	in.JVM.DeepCopyInto(&out.JVM)
+	if in.Metrics != nil {
+		in, out := &in.Metrics, &out.Metrics
+		*out = new(JanusgraphMetricsSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	in.ScaleDown.DeepCopyInto(&out.ScaleDown)
+	in.Cleanup.DeepCopyInto(&out.Cleanup)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphSpec.
+func (in *JanusgraphSpec) DeepCopy() *JanusgraphSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphStatus) DeepCopyInto(out *JanusgraphStatus) {
+	*out = *in
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Warnings != nil {
+		in, out := &in.Warnings, &out.Warnings
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ScaleDown != nil {
+		in, out := &in.ScaleDown, &out.ScaleDown
+		*out = new(JanusgraphScaleDownStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Cleanup != nil {
+		in, out := &in.Cleanup, &out.Cleanup
+		*out = new(JanusgraphCleanupStatus)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphStatus.
+func (in *JanusgraphStatus) DeepCopy() *JanusgraphStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphStorageSpec) DeepCopyInto(out *JanusgraphStorageSpec) {
+	*out = *in
+	out.Size = in.Size.DeepCopy()
+	if in.StorageClassName != nil {
+		in, out := &in.StorageClassName, &out.StorageClassName
+		*out = new(string)
+		**out = **in
+	}
+	if in.AccessModes != nil {
+		in, out := &in.AccessModes, &out.AccessModes
+		*out = make([]v1.PersistentVolumeAccessMode, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphStorageSpec.
+func (in *JanusgraphStorageSpec) DeepCopy() *JanusgraphStorageSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphStorageSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphTLSSpec) DeepCopyInto(out *JanusgraphTLSSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphTLSSpec.
+func (in *JanusgraphTLSSpec) DeepCopy() *JanusgraphTLSSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphTLSSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MixedIndex) DeepCopyInto(out *MixedIndex) {
+	*out = *in
+	if in.Keys != nil {
+		in, out := &in.Keys, &out.Keys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MixedIndex.
+func (in *MixedIndex) DeepCopy() *MixedIndex {
+	if in == nil {
+		return nil
+	}
+	out := new(MixedIndex)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PersistentVolumeClaimFile) DeepCopyInto(out *PersistentVolumeClaimFile) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PersistentVolumeClaimFile.
+func (in *PersistentVolumeClaimFile) DeepCopy() *PersistentVolumeClaimFile {
+	if in == nil {
+		return nil
+	}
+	out := new(PersistentVolumeClaimFile)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PersistentVolumeClaimStorage) DeepCopyInto(out *PersistentVolumeClaimStorage) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PersistentVolumeClaimStorage.
+func (in *PersistentVolumeClaimStorage) DeepCopy() *PersistentVolumeClaimStorage {
+	if in == nil {
+		return nil
+	}
+	out := new(PersistentVolumeClaimStorage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PropertyKey) DeepCopyInto(out *PropertyKey) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PropertyKey.
+func (in *PropertyKey) DeepCopy() *PropertyKey {
+	if in == nil {
+		return nil
+	}
+	out := new(PropertyKey)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *S3Storage) DeepCopyInto(out *S3Storage) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new S3Storage.
+func (in *S3Storage) DeepCopy() *S3Storage {
+	if in == nil {
+		return nil
+	}
+	out := new(S3Storage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceMonitorSpec) DeepCopyInto(out *ServiceMonitorSpec) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceMonitorSpec.
+func (in *ServiceMonitorSpec) DeepCopy() *ServiceMonitorSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceMonitorSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VertexLabel) DeepCopyInto(out *VertexLabel) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VertexLabel.
+func (in *VertexLabel) DeepCopy() *VertexLabel {
+	if in == nil {
+		return nil
+	}
+	out := new(VertexLabel)
+	in.DeepCopyInto(out)
+	return out
+}
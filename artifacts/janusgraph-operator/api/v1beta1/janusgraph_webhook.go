@@ -0,0 +1,231 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var janusgraphlog = logf.Log.WithName("janusgraph-resource")
+
+// DefaultJanusgraphVersion is the JanusGraph version used when Spec.Image.Version is empty
+const DefaultJanusgraphVersion = "latest"
+
+// DefaultJanusgraphImage is the JanusGraph image repository used when Spec.Image.Repository is empty
+const DefaultJanusgraphImage = "horeaporutiu/janusgraph"
+
+// versionPattern matches a valid image tag, the same pattern as the OpenAPI validation of Spec.Image.Version
+var versionPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+
+// releasePattern matches the numeric part of a release version, e.g. 0.5.3 in v0.5.3-hadoop2
+var releasePattern = regexp.MustCompile(`^v?(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// SetupWebhookWithManager registers the defaulting, validating and conversion webhooks of Janusgraph with the manager.
+// Requests for v1alpha1 objects are converted to v1beta1 before they reach the defaulting and validating webhooks.
+func (r *Janusgraph) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// Hub marks v1beta1 as the version the other versions of Janusgraph are converted to and from
+func (*Janusgraph) Hub() {}
+
+// +kubebuilder:webhook:path=/mutate-graph-example-com-v1beta1-janusgraph,mutating=true,failurePolicy=fail,sideEffects=None,groups=graph.example.com,resources=janusgraphs,verbs=create;update,versions=v1beta1,name=mjanusgraph.kb.io,admissionReviewVersions={v1,v1beta1}
+
+var _ webhook.Defaulter = &Janusgraph{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type
+func (r *Janusgraph) Default() {
+	janusgraphlog.Info("default", "name", r.Name)
+
+	if r.Spec.Replicas == 0 {
+		r.Spec.Replicas = 1
+	}
+	if r.Spec.Image.Repository == "" {
+		r.Spec.Image.Repository = DefaultJanusgraphImage
+	}
+	if r.Spec.Image.Version == "" {
+		r.Spec.Image.Version = DefaultJanusgraphVersion
+	}
+}
+
+// +kubebuilder:webhook:path=/validate-graph-example-com-v1beta1-janusgraph,mutating=false,failurePolicy=fail,sideEffects=None,groups=graph.example.com,resources=janusgraphs,verbs=create;update,versions=v1beta1,name=vjanusgraph.kb.io,admissionReviewVersions={v1,v1beta1}
+
+var _ webhook.Validator = &Janusgraph{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *Janusgraph) ValidateCreate() error {
+	janusgraphlog.Info("validate create", "name", r.Name)
+
+	return r.invalid(r.validateSpec())
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *Janusgraph) ValidateUpdate(old runtime.Object) error {
+	janusgraphlog.Info("validate update", "name", r.Name)
+
+	allErrs := r.validateSpec()
+	if oldJanusgraph, ok := old.(*Janusgraph); ok {
+		allErrs = append(allErrs, r.validateStorageUpdate(oldJanusgraph)...)
+		allErrs = append(allErrs, r.validateVersionUpdate(oldJanusgraph)...)
+	}
+	return r.invalid(allErrs)
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *Janusgraph) ValidateDelete() error {
+	janusgraphlog.Info("validate delete", "name", r.Name)
+
+	return nil
+}
+
+//validateSpec checks the fields of the spec that the OpenAPI schema cannot check on its own
+func (r *Janusgraph) validateSpec() field.ErrorList {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+
+	if r.Spec.Replicas < 1 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("replicas"), r.Spec.Replicas, "must be at least 1"))
+	}
+	if !versionPattern.MatchString(r.Spec.Image.Version) {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("image", "version"), r.Spec.Image.Version, "must be a valid image tag"))
+	}
+
+	service := r.Spec.Service
+	if service.NodePort != 0 {
+		nodePortPath := specPath.Child("service", "nodePort")
+		if service.Type != "NodePort" && service.Type != "LoadBalancer" {
+			allErrs = append(allErrs, field.Invalid(nodePortPath, service.NodePort, "can only be set when the service type is NodePort or LoadBalancer"))
+		} else if service.NodePort < 30000 || service.NodePort > 32767 {
+			allErrs = append(allErrs, field.Invalid(nodePortPath, service.NodePort, "must be in the range 30000-32767"))
+		}
+	}
+
+	if auth := r.Spec.Auth; auth != nil {
+		authPath := specPath.Child("auth")
+		switch auth.Type {
+		case "Simple":
+			if auth.CredentialsSecret == "" {
+				allErrs = append(allErrs, field.Required(authPath.Child("credentialsSecret"), "required for Simple auth"))
+			}
+		case "Kerberos":
+			if auth.KerberosSecret == "" {
+				allErrs = append(allErrs, field.Required(authPath.Child("kerberosSecret"), "required for Kerberos auth"))
+			}
+			if auth.Principal == "" {
+				allErrs = append(allErrs, field.Required(authPath.Child("principal"), "required for Kerberos auth"))
+			}
+		}
+	}
+
+	jvm := r.Spec.JVM
+	if jvm.HeapMin != nil && jvm.HeapMax != nil && jvm.HeapMin.Cmp(*jvm.HeapMax) > 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("jvm", "heapMin"), jvm.HeapMin.String(), "must not be larger than heapMax"))
+	}
+	return allErrs
+}
+
+//validateStorageUpdate rejects changes to the storage of a Janusgraph other than growing its size.
+//The volume claim templates of a StatefulSet cannot be changed once it has been created.
+func (r *Janusgraph) validateStorageUpdate(old *Janusgraph) field.ErrorList {
+	var allErrs field.ErrorList
+	storagePath := field.NewPath("spec", "storage")
+
+	if (old.Spec.Storage == nil) != (r.Spec.Storage == nil) {
+		return append(allErrs, field.Forbidden(storagePath, "cannot be added or removed after the Janusgraph is created"))
+	}
+	if r.Spec.Storage == nil {
+		return allErrs
+	}
+	storage, oldStorage := r.Spec.Storage, old.Spec.Storage
+	if !reflect.DeepEqual(storage.StorageClassName, oldStorage.StorageClassName) {
+		allErrs = append(allErrs, field.Forbidden(storagePath.Child("storageClassName"), "is immutable"))
+	}
+	if !reflect.DeepEqual(storage.AccessModes, oldStorage.AccessModes) {
+		allErrs = append(allErrs, field.Forbidden(storagePath.Child("accessModes"), "is immutable"))
+	}
+	if storage.Size.Cmp(oldStorage.Size) < 0 {
+		allErrs = append(allErrs, field.Invalid(storagePath.Child("size"), storage.Size.String(), "can only grow, volumes cannot be shrunk"))
+	}
+	return allErrs
+}
+
+//validateVersionUpdate rejects downgrades of the JanusGraph version, whose storage format may not be
+//readable by an older release. Versions that are not release numbers, e.g. latest, are not compared.
+func (r *Janusgraph) validateVersionUpdate(old *Janusgraph) field.ErrorList {
+	var allErrs field.ErrorList
+	version, ok := releaseVersion(r.Spec.Image.Version)
+	if !ok {
+		return allErrs
+	}
+	oldVersion, ok := releaseVersion(old.Spec.Image.Version)
+	if !ok {
+		return allErrs
+	}
+	for i := range version {
+		if version[i] > oldVersion[i] {
+			break
+		}
+		if version[i] < oldVersion[i] {
+			allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "image", "version"),
+				"downgrading from "+old.Spec.Image.Version+" to "+r.Spec.Image.Version+" is not supported"))
+			break
+		}
+	}
+	return allErrs
+}
+
+//invalid returns an Invalid error for the given field errors, or nil if there are none
+func (r *Janusgraph) invalid(allErrs field.ErrorList) error {
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(schema.GroupKind{Group: GroupVersion.Group, Kind: "Janusgraph"}, r.Name, allErrs)
+}
+
+//releaseVersion returns the major, minor and patch numbers of a release version, e.g. [0 5 3] for 0.5.3
+func releaseVersion(version string) ([3]int, bool) {
+	var release [3]int
+	match := releasePattern.FindStringSubmatch(strings.TrimSpace(version))
+	if match == nil {
+		return release, false
+	}
+	for i := range release {
+		if match[i+1] == "" {
+			continue
+		}
+		number, err := strconv.Atoi(match[i+1])
+		if err != nil {
+			return release, false
+		}
+		release[i] = number
+	}
+	return release, true
+}
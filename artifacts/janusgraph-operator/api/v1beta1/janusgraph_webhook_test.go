@@ -0,0 +1,112 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	graphv1alpha1 "github.com/example/janusgraph-operator/api/v1alpha1"
+	graphv1beta1 "github.com/example/janusgraph-operator/api/v1beta1"
+)
+
+var _ = Describe("Janusgraph webhooks", func() {
+	const (
+		timeout  = 10 * time.Second
+		interval = 250 * time.Millisecond
+	)
+
+	var (
+		ctx context.Context
+		key types.NamespacedName
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "janusgraph-webhook-test-"}}
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+		key = types.NamespacedName{Name: "janusgraph-sample", Namespace: ns.Name}
+	})
+
+	It("serves a v1alpha1 Janusgraph converted to v1beta1", func() {
+		janusgraph := &graphv1alpha1.Janusgraph{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+			Spec: graphv1alpha1.JanusgraphSpec{
+				Size:    3,
+				Image:   "example/janusgraph",
+				Version: "0.5.3",
+			},
+		}
+		Expect(k8sClient.Create(ctx, janusgraph)).To(Succeed())
+
+		converted := &graphv1beta1.Janusgraph{}
+		Eventually(func() error {
+			return k8sClient.Get(ctx, key, converted)
+		}, timeout, interval).Should(Succeed())
+		Expect(converted.Spec.Replicas).To(Equal(int32(3)))
+		Expect(converted.Spec.Image).To(Equal(graphv1beta1.JanusgraphImageSpec{
+			Repository: "example/janusgraph",
+			Version:    "0.5.3",
+		}))
+
+		// and back, for the clients still using v1alpha1
+		Expect(k8sClient.Get(ctx, key, janusgraph)).To(Succeed())
+		Expect(janusgraph.Spec.Size).To(Equal(int32(3)))
+		Expect(janusgraph.Spec.Version).To(Equal("0.5.3"))
+	})
+
+	It("defaults a v1alpha1 Janusgraph with the webhook of v1beta1", func() {
+		janusgraph := &graphv1alpha1.Janusgraph{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+		}
+		Expect(k8sClient.Create(ctx, janusgraph)).To(Succeed())
+
+		converted := &graphv1beta1.Janusgraph{}
+		Expect(k8sClient.Get(ctx, key, converted)).To(Succeed())
+		Expect(converted.Spec.Replicas).To(Equal(int32(1)))
+		Expect(converted.Spec.Image.Repository).To(Equal(graphv1beta1.DefaultJanusgraphImage))
+	})
+
+	It("rejects a v1alpha1 Janusgraph with a negative size", func() {
+		janusgraph := &graphv1alpha1.Janusgraph{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+			Spec:       graphv1alpha1.JanusgraphSpec{Size: -3},
+		}
+		err := k8sClient.Create(ctx, janusgraph)
+		Expect(apierrors.IsInvalid(err)).To(BeTrue(), "expected an invalid error, got %v", err)
+		Expect(err.Error()).To(ContainSubstring("spec.size"))
+	})
+
+	It("rejects a v1alpha1 Janusgraph with the validating webhook of v1beta1", func() {
+		janusgraph := &graphv1alpha1.Janusgraph{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+			Spec: graphv1alpha1.JanusgraphSpec{
+				Service: graphv1alpha1.JanusgraphServiceSpec{Type: corev1.ServiceTypeClusterIP, NodePort: 30080},
+			},
+		}
+		err := k8sClient.Create(ctx, janusgraph)
+		Expect(apierrors.IsInvalid(err)).To(BeTrue(), "expected an invalid error, got %v", err)
+		Expect(err.Error()).To(ContainSubstring("can only be set when the service type is NodePort or LoadBalancer"))
+	})
+})
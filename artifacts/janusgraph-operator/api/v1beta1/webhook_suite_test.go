@@ -0,0 +1,176 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1_test
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/controller-runtime/pkg/envtest/printer"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/yaml"
+
+	graphv1alpha1 "github.com/example/janusgraph-operator/api/v1alpha1"
+	graphv1beta1 "github.com/example/janusgraph-operator/api/v1beta1"
+	// +kubebuilder:scaffold:imports
+)
+
+// These tests use Ginkgo (BDD-style Go testing framework). Refer to
+// http://onsi.github.io/ginkgo/ to learn more about Ginkgo.
+
+var k8sClient client.Client
+var testEnv *envtest.Environment
+var stopManager context.CancelFunc
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecsWithDefaultAndCustomReporters(t,
+		"Webhook Suite",
+		[]Reporter{printer.NewlineReporter{}})
+}
+
+var _ = BeforeSuite(func(done Done) {
+	logf.SetLogger(zap.New(zap.WriteTo(GinkgoWriter), zap.UseDevMode(true)))
+
+	By("bootstrapping test environment")
+	testEnv = &envtest.Environment{
+		CRDDirectoryPaths: []string{filepath.Join("..", "..", "config", "crd", "bases")},
+		WebhookInstallOptions: envtest.WebhookInstallOptions{
+			Paths: []string{filepath.Join("..", "..", "config", "webhook")},
+		},
+	}
+
+	cfg, err := testEnv.Start()
+	Expect(err).NotTo(HaveOccurred())
+	Expect(cfg).NotTo(BeNil())
+
+	scheme := runtime.NewScheme()
+	err = clientgoscheme.AddToScheme(scheme)
+	Expect(err).NotTo(HaveOccurred())
+	err = apiextensionsv1.AddToScheme(scheme)
+	Expect(err).NotTo(HaveOccurred())
+	err = graphv1alpha1.AddToScheme(scheme)
+	Expect(err).NotTo(HaveOccurred())
+	err = graphv1beta1.AddToScheme(scheme)
+	Expect(err).NotTo(HaveOccurred())
+
+	// +kubebuilder:scaffold:scheme
+
+	k8sClient, err = client.New(cfg, client.Options{Scheme: scheme})
+	Expect(err).NotTo(HaveOccurred())
+	Expect(k8sClient).NotTo(BeNil())
+
+	// envtest installs the bases, the conversion of config/crd/patches is applied here
+	webhookInstallOptions := &testEnv.WebhookInstallOptions
+	err = enableConversion(filepath.Join("..", "..", "config", "crd", "patches", "webhook_in_janusgraphs.yaml"), webhookInstallOptions)
+	Expect(err).NotTo(HaveOccurred())
+
+	// the webhooks run in a manager serving on the address envtest registered them with
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+		Scheme:             scheme,
+		Host:               webhookInstallOptions.LocalServingHost,
+		Port:               webhookInstallOptions.LocalServingPort,
+		CertDir:            webhookInstallOptions.LocalServingCertDir,
+		MetricsBindAddress: "0",
+	})
+	Expect(err).NotTo(HaveOccurred())
+	err = (&graphv1beta1.Janusgraph{}).SetupWebhookWithManager(mgr)
+	Expect(err).NotTo(HaveOccurred())
+	err = (&graphv1alpha1.JanusgraphIndexJob{}).SetupWebhookWithManager(mgr)
+	Expect(err).NotTo(HaveOccurred())
+
+	var ctx context.Context
+	ctx, stopManager = context.WithCancel(context.Background())
+	go func() {
+		defer GinkgoRecover()
+		err := mgr.Start(ctx)
+		Expect(err).NotTo(HaveOccurred())
+	}()
+
+	// wait for the webhook server to be ready
+	dialer := &net.Dialer{Timeout: time.Second}
+	address := net.JoinHostPort(webhookInstallOptions.LocalServingHost, strconv.Itoa(webhookInstallOptions.LocalServingPort))
+	Eventually(func() error {
+		conn, err := tls.DialWithDialer(dialer, "tcp", address, &tls.Config{InsecureSkipVerify: true})
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}).Should(Succeed())
+
+	close(done)
+}, 60)
+
+var _ = AfterSuite(func() {
+	By("tearing down the test environment")
+	if stopManager != nil {
+		stopManager()
+	}
+	err := testEnv.Stop()
+	Expect(err).NotTo(HaveOccurred())
+})
+
+// enableConversion sets the conversion of a CRD patch on the installed CRD. The patch calls the
+// webhook-service of the manager, the test calls the local webhook server of envtest instead.
+func enableConversion(patchPath string, options *envtest.WebhookInstallOptions) error {
+	data, err := ioutil.ReadFile(patchPath)
+	if err != nil {
+		return err
+	}
+	patch := &apiextensionsv1.CustomResourceDefinition{}
+	if err := yaml.Unmarshal(data, patch); err != nil {
+		return err
+	}
+	conversion := patch.Spec.Conversion
+	service := conversion.Webhook.ClientConfig.Service
+	url := fmt.Sprintf("https://%s/%s",
+		net.JoinHostPort(options.LocalServingHost, strconv.Itoa(options.LocalServingPort)),
+		strings.TrimPrefix(*service.Path, "/"))
+	conversion.Webhook.ClientConfig = &apiextensionsv1.WebhookClientConfig{
+		URL:      &url,
+		CABundle: options.LocalServingCAData,
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		crd := &apiextensionsv1.CustomResourceDefinition{}
+		if err := k8sClient.Get(context.Background(), types.NamespacedName{Name: patch.Name}, crd); err != nil {
+			return err
+		}
+		crd.Spec.Conversion = conversion
+		return k8sClient.Update(context.Background(), crd)
+	})
+}
@@ -0,0 +1,492 @@
+//go:build !ignore_autogenerated
+// +build !ignore_autogenerated
+
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by controller-gen. DO NOT EDIT.
+
+package v1beta1
+
+import (
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+)
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupStorage) DeepCopyInto(out *BackupStorage) {
+	*out = *in
+	if in.PersistentVolumeClaim != nil {
+		in, out := &in.PersistentVolumeClaim, &out.PersistentVolumeClaim
+		*out = new(PersistentVolumeClaimStorage)
+		**out = **in
+	}
+	if in.S3 != nil {
+		in, out := &in.S3, &out.S3
+		*out = new(S3Storage)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupStorage.
+func (in *BackupStorage) DeepCopy() *BackupStorage {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupStorage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Janusgraph) DeepCopyInto(out *Janusgraph) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Janusgraph.
+func (in *Janusgraph) DeepCopy() *Janusgraph {
+	if in == nil {
+		return nil
+	}
+	out := new(Janusgraph)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Janusgraph) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphAuthSpec) DeepCopyInto(out *JanusgraphAuthSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphAuthSpec.
+func (in *JanusgraphAuthSpec) DeepCopy() *JanusgraphAuthSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphAuthSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphCleanupSpec) DeepCopyInto(out *JanusgraphCleanupSpec) {
+	*out = *in
+	if in.FinalBackup != nil {
+		in, out := &in.FinalBackup, &out.FinalBackup
+		*out = new(BackupStorage)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphCleanupSpec.
+func (in *JanusgraphCleanupSpec) DeepCopy() *JanusgraphCleanupSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphCleanupSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphCleanupStatus) DeepCopyInto(out *JanusgraphCleanupStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphCleanupStatus.
+func (in *JanusgraphCleanupStatus) DeepCopy() *JanusgraphCleanupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphCleanupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphImageSpec) DeepCopyInto(out *JanusgraphImageSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphImageSpec.
+func (in *JanusgraphImageSpec) DeepCopy() *JanusgraphImageSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphImageSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphJVMSpec) DeepCopyInto(out *JanusgraphJVMSpec) {
+	*out = *in
+	if in.HeapMax != nil {
+		in, out := &in.HeapMax, &out.HeapMax
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.HeapMin != nil {
+		in, out := &in.HeapMin, &out.HeapMin
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.GCOptions != nil {
+		in, out := &in.GCOptions, &out.GCOptions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExtraOptions != nil {
+		in, out := &in.ExtraOptions, &out.ExtraOptions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphJVMSpec.
+func (in *JanusgraphJVMSpec) DeepCopy() *JanusgraphJVMSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphJVMSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphList) DeepCopyInto(out *JanusgraphList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Janusgraph, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphList.
+func (in *JanusgraphList) DeepCopy() *JanusgraphList {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *JanusgraphList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphMetricsSpec) DeepCopyInto(out *JanusgraphMetricsSpec) {
+	*out = *in
+	if in.ServiceMonitor != nil {
+		in, out := &in.ServiceMonitor, &out.ServiceMonitor
+		*out = new(ServiceMonitorSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphMetricsSpec.
+func (in *JanusgraphMetricsSpec) DeepCopy() *JanusgraphMetricsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphMetricsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphProbesSpec) DeepCopyInto(out *JanusgraphProbesSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphProbesSpec.
+func (in *JanusgraphProbesSpec) DeepCopy() *JanusgraphProbesSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphProbesSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphScaleDownSpec) DeepCopyInto(out *JanusgraphScaleDownSpec) {
+	*out = *in
+	if in.DrainTimeoutSeconds != nil {
+		in, out := &in.DrainTimeoutSeconds, &out.DrainTimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphScaleDownSpec.
+func (in *JanusgraphScaleDownSpec) DeepCopy() *JanusgraphScaleDownSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphScaleDownSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphScaleDownStatus) DeepCopyInto(out *JanusgraphScaleDownStatus) {
+	*out = *in
+	if in.Pods != nil {
+		in, out := &in.Pods, &out.Pods
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.StartTime.DeepCopyInto(&out.StartTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphScaleDownStatus.
+func (in *JanusgraphScaleDownStatus) DeepCopy() *JanusgraphScaleDownStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphScaleDownStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphServiceSpec) DeepCopyInto(out *JanusgraphServiceSpec) {
+	*out = *in
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.LoadBalancerSourceRanges != nil {
+		in, out := &in.LoadBalancerSourceRanges, &out.LoadBalancerSourceRanges
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphServiceSpec.
+func (in *JanusgraphServiceSpec) DeepCopy() *JanusgraphServiceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphServiceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphSpec) DeepCopyInto(out *JanusgraphSpec) {
+	*out = *in
+	out.Image = in.Image
+	in.Service.DeepCopyInto(&out.Service)
+	out.Probes = in.Probes
+	if in.Auth != nil {
+		in, out := &in.Auth, &out.Auth
+		*out = new(JanusgraphAuthSpec)
+		**out = **in
+	}
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(JanusgraphTLSSpec)
+		**out = **in
+	}
+	if in.Storage != nil {
+		in, out := &in.Storage, &out.Storage
+		*out = new(JanusgraphStorageSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	in.Resources.DeepCopyInto(&out.Resources)
+	in.JVM.DeepCopyInto(&out.JVM)
+	if in.Metrics != nil {
+		in, out := &in.Metrics, &out.Metrics
+		*out = new(JanusgraphMetricsSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	in.ScaleDown.DeepCopyInto(&out.ScaleDown)
+	in.Cleanup.DeepCopyInto(&out.Cleanup)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphSpec.
+func (in *JanusgraphSpec) DeepCopy() *JanusgraphSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphStatus) DeepCopyInto(out *JanusgraphStatus) {
+	*out = *in
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Warnings != nil {
+		in, out := &in.Warnings, &out.Warnings
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ScaleDown != nil {
+		in, out := &in.ScaleDown, &out.ScaleDown
+		*out = new(JanusgraphScaleDownStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Cleanup != nil {
+		in, out := &in.Cleanup, &out.Cleanup
+		*out = new(JanusgraphCleanupStatus)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphStatus.
+func (in *JanusgraphStatus) DeepCopy() *JanusgraphStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphStorageSpec) DeepCopyInto(out *JanusgraphStorageSpec) {
+	*out = *in
+	out.Size = in.Size.DeepCopy()
+	if in.StorageClassName != nil {
+		in, out := &in.StorageClassName, &out.StorageClassName
+		*out = new(string)
+		**out = **in
+	}
+	if in.AccessModes != nil {
+		in, out := &in.AccessModes, &out.AccessModes
+		*out = make([]v1.PersistentVolumeAccessMode, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphStorageSpec.
+func (in *JanusgraphStorageSpec) DeepCopy() *JanusgraphStorageSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphStorageSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JanusgraphTLSSpec) DeepCopyInto(out *JanusgraphTLSSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JanusgraphTLSSpec.
+func (in *JanusgraphTLSSpec) DeepCopy() *JanusgraphTLSSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JanusgraphTLSSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PersistentVolumeClaimStorage) DeepCopyInto(out *PersistentVolumeClaimStorage) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PersistentVolumeClaimStorage.
+func (in *PersistentVolumeClaimStorage) DeepCopy() *PersistentVolumeClaimStorage {
+	if in == nil {
+		return nil
+	}
+	out := new(PersistentVolumeClaimStorage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *S3Storage) DeepCopyInto(out *S3Storage) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new S3Storage.
+func (in *S3Storage) DeepCopy() *S3Storage {
+	if in == nil {
+		return nil
+	}
+	out := new(S3Storage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceMonitorSpec) DeepCopyInto(out *ServiceMonitorSpec) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceMonitorSpec.
+func (in *ServiceMonitorSpec) DeepCopy() *ServiceMonitorSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceMonitorSpec)
+	in.DeepCopyInto(out)
+	return out
+}
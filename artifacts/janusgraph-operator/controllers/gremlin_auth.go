@@ -0,0 +1,184 @@
+/*
+Copyright 2021.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	graphv1beta1 "github.com/example/janusgraph-operator/api/v1beta1"
+)
+
+const (
+	authTypeSimple   = "Simple"
+	authTypeKerberos = "Kerberos"
+)
+
+// gremlinAuthPath is where the Kerberos Secret is mounted in JanusGraph pods and Gremlin script Jobs
+const gremlinAuthPath = "/etc/janusgraph-auth"
+
+// gremlinTLSPath is where the keystore Secret is mounted in JanusGraph pods
+const gremlinTLSPath = "/etc/janusgraph-tls"
+
+// simpleAuthenticator creates the user of the credentials Secret in an in-memory credentials graph
+// every time the Gremlin Server starts, so changing the Secret only needs a restart of the pods
+const simpleAuthenticator = "org.janusgraph.graphdb.tinkerpop.gremlin.server.auth.JanusGraphSimpleAuthenticator"
+
+// kerberosAuthenticator accepts clients holding a Kerberos ticket for the service principal
+const kerberosAuthenticator = "org.apache.tinkerpop.gremlin.server.auth.Krb5Authenticator"
+
+// kerberosJaasEntry is the JAAS login entry Gremlin script Jobs use to log in with the keytab
+const kerberosJaasEntry = "GremlinClient"
+
+// secretFileMode is the mode of the files projected from Secrets. It is set explicitly so the
+// volumes compare equal to the ones read back from the API server.
+var secretFileMode = int32(0400)
+
+// gremlinServerSecurity returns the environment, volume mounts and volumes that enable auth and TLS
+// on the Gremlin Server. The JanusGraph image writes gremlinserver.* environment variables into
+// gremlin-server.yaml on start, so passwords are read from Secrets and never stored in the StatefulSet.
+// The Kerberos configuration is passed to the JVM by javaOptionsForJanusgraph.
+func gremlinServerSecurity(m *graphv1beta1.Janusgraph) ([]corev1.EnvVar, []corev1.VolumeMount, []corev1.Volume) {
+	var env []corev1.EnvVar
+	var mounts []corev1.VolumeMount
+	var volumes []corev1.Volume
+
+	if auth := m.Spec.Auth; auth != nil {
+		switch auth.Type {
+		case authTypeSimple:
+			env = append(env,
+				corev1.EnvVar{Name: "gremlinserver.authentication.authenticator", Value: simpleAuthenticator},
+				corev1.EnvVar{Name: "gremlinserver.authentication.config.credentialsDb", Value: "conf/janusgraph-inmemory.properties"},
+				secretEnvVar("gremlinserver.authentication.config.defaultUsername", auth.CredentialsSecret, "username"),
+				secretEnvVar("gremlinserver.authentication.config.defaultPassword", auth.CredentialsSecret, "password"),
+				//read by the probes
+				secretEnvVar("GREMLIN_USERNAME", auth.CredentialsSecret, "username"),
+				secretEnvVar("GREMLIN_PASSWORD", auth.CredentialsSecret, "password"),
+			)
+		case authTypeKerberos:
+			env = append(env,
+				corev1.EnvVar{Name: "gremlinserver.authentication.authenticator", Value: kerberosAuthenticator},
+				corev1.EnvVar{Name: "gremlinserver.authentication.config.principal", Value: auth.Principal},
+				corev1.EnvVar{Name: "gremlinserver.authentication.config.keytab", Value: gremlinAuthPath + "/keytab"},
+			)
+			mounts = append(mounts, corev1.VolumeMount{Name: "auth", MountPath: gremlinAuthPath, ReadOnly: true})
+			volumes = append(volumes, secretVolume("auth", auth.KerberosSecret))
+		}
+	}
+
+	if tls := m.Spec.TLS; tls != nil {
+		keystoreType := tls.KeystoreType
+		if keystoreType == "" {
+			keystoreType = "PKCS12"
+		}
+		env = append(env,
+			corev1.EnvVar{Name: "gremlinserver.ssl.enabled", Value: "true"},
+			corev1.EnvVar{Name: "gremlinserver.ssl.keyStore", Value: gremlinTLSPath + "/keystore"},
+			corev1.EnvVar{Name: "gremlinserver.ssl.keyStoreType", Value: keystoreType},
+			secretEnvVar("gremlinserver.ssl.keyStorePassword", tls.KeystoreSecret, "password"),
+		)
+		mounts = append(mounts, corev1.VolumeMount{Name: "tls", MountPath: gremlinTLSPath, ReadOnly: true})
+		volumes = append(volumes, secretVolume("tls", tls.KeystoreSecret))
+	}
+	return env, mounts, volumes
+}
+
+// gremlinClientSecurity returns the environment, volume mounts and volumes a Gremlin script Job needs
+// to authenticate to the Gremlin Server. Simple credentials are passed through the environment,
+// see gremlinConnect; Kerberos Jobs log in with the keytab of the service principal.
+func gremlinClientSecurity(m *graphv1beta1.Janusgraph) ([]corev1.EnvVar, []corev1.VolumeMount, []corev1.Volume) {
+	auth := m.Spec.Auth
+	if auth == nil {
+		return nil, nil, nil
+	}
+	switch auth.Type {
+	case authTypeSimple:
+		return []corev1.EnvVar{
+			secretEnvVar("GREMLIN_USERNAME", auth.CredentialsSecret, "username"),
+			secretEnvVar("GREMLIN_PASSWORD", auth.CredentialsSecret, "password"),
+		}, nil, nil
+	case authTypeKerberos:
+		javaOptions := "-Djava.security.krb5.conf=" + gremlinAuthPath + "/krb5.conf" +
+			" -Djava.security.auth.login.config=" + gremlinScriptsPath + "/jaas.conf"
+		return []corev1.EnvVar{{Name: "JAVA_OPTIONS", Value: javaOptions}},
+			[]corev1.VolumeMount{{Name: "auth", MountPath: gremlinAuthPath, ReadOnly: true}},
+			[]corev1.Volume{secretVolume("auth", auth.KerberosSecret)}
+	}
+	return nil, nil, nil
+}
+
+// kerberosJaasConfig returns the JAAS configuration of Gremlin script Jobs of a Janusgraph with Kerberos auth
+func kerberosJaasConfig(m *graphv1beta1.Janusgraph) string {
+	return kerberosJaasEntry + ` {
+  com.sun.security.auth.module.Krb5LoginModule required
+  useKeyTab=true
+  storeKey=true
+  doNotPrompt=true
+  keyTab="` + gremlinAuthPath + `/keytab"
+  principal="` + m.Spec.Auth.Principal + `";
+};
+`
+}
+
+// kerberosServiceName returns the service part of a Kerberos principal, e.g. gremlin for gremlin/host@REALM
+func kerberosServiceName(principal string) string {
+	if i := strings.IndexAny(principal, "/@"); i >= 0 {
+		return principal[:i]
+	}
+	return principal
+}
+
+// gremlinScheme returns the URL scheme of the Gremlin Server HTTP endpoint
+func gremlinScheme(m *graphv1beta1.Janusgraph) string {
+	if m.Spec.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// warningsForJanusgraph returns the problems with the spec of a JanusGraph object that are reported in its status
+func warningsForJanusgraph(m *graphv1beta1.Janusgraph) []string {
+	var warnings []string
+	if m.Spec.Service.Type == corev1.ServiceTypeLoadBalancer && m.Spec.Auth == nil {
+		warnings = append(warnings, "the Gremlin Server is exposed through a LoadBalancer Service without auth")
+	}
+	return warnings
+}
+
+// secretEnvVar returns an environment variable read from a key of a Secret
+func secretEnvVar(name string, secretName string, key string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: name,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+				Key:                  key,
+			},
+		},
+	}
+}
+
+// secretVolume returns a volume projecting every key of a Secret as a file
+func secretVolume(name string, secretName string) corev1.Volume {
+	return corev1.Volume{
+		Name: name,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName:  secretName,
+				DefaultMode: &secretFileMode,
+			},
+		},
+	}
+}
@@ -0,0 +1,242 @@
+/*
+Copyright 2021.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	graphv1beta1 "github.com/example/janusgraph-operator/api/v1beta1"
+)
+
+// gremlinContainerName is the name of the container running the Gremlin Console in a Gremlin script Job
+const gremlinContainerName = "gremlin"
+
+// maxJobNameLength is the longest name of a Job, since the Job controller sets it as the value of the
+// job-name label of its pods
+const maxJobNameLength = validation.LabelValueMaxLength
+
+// gremlinScriptsPath is where the ConfigMap of a Gremlin script Job is mounted
+const gremlinScriptsPath = "/etc/janusgraph-scripts"
+
+// gremlinConnect opens the driver Cluster of the runner scripts. Credentials of Simple auth are read
+// from the environment of the Job so they are never stored in the ConfigMap.
+const gremlinConnect = `builder = Cluster.build(new File('` + gremlinScriptsPath + `/remote.yaml'))
+if (System.getenv('GREMLIN_USERNAME')) {
+    builder.credentials(System.getenv('GREMLIN_USERNAME'), System.getenv('GREMLIN_PASSWORD'))
+}
+cluster = builder.create()
+`
+
+// localGraph defines the functions of the runner scripts that hold a graph in the Job. openGraph opens a
+// TinkerGraph that keeps every value of a multi-valued property. readGraphSON reads a GraphSON file with the
+// reader matching the GraphSON writer of the Gremlin Server, which knows the JanusGraph types such as edge ids.
+const localGraph = `openGraph = {
+    conf = new org.apache.commons.configuration.BaseConfiguration()
+    conf.setProperty('gremlin.tinkergraph.defaultVertexPropertyCardinality', 'list')
+    TinkerGraph.open(conf)
+}
+readGraphSON = { graph, String path ->
+    io = graph.io(IoCore.graphson())
+    mapper = io.mapper().addRegistry(org.janusgraph.graphdb.tinkerpop.JanusGraphIoRegistry.instance()).create()
+    new File(path).withInputStream { stream -> io.reader().mapper(mapper).create().readGraph(stream, graph) }
+}
+`
+
+// gremlinRunnerScript is the runner of Gremlin script Jobs that execute a single server side script.
+// It submits script.groovy to the Gremlin Server of the JanusGraph instance and writes the
+// string returned by the script to the termination log, where the operator reads it back.
+const gremlinRunnerScript = gremlinConnect + `client = cluster.connect()
+exitCode = 0
+try {
+    options = org.apache.tinkerpop.gremlin.driver.RequestOptions.build().timeout(System.getenv('SCRIPT_TIMEOUT_MS') as long).create()
+    result = client.submit(new File('` + gremlinScriptsPath + `/script.groovy').text, options).all().get()
+    new File('/dev/termination-log').text = result.isEmpty() ? '' : result[0].getString()
+} catch (Exception e) {
+    new File('/dev/termination-log').text = e.getMessage() ?: e.toString()
+    exitCode = 1
+} finally {
+    client.close()
+    cluster.close()
+}
+System.exit(exitCode)
+`
+
+// gremlinRemoteConfig returns the Gremlin driver configuration pointing at the Service of a JanusGraph object.
+// Results are serialized to strings so the scripts do not depend on JanusGraph specific serializers.
+// The certificate of a TLS enabled Gremlin Server is not verified since the Jobs only connect to the
+// in-cluster Service.
+func gremlinRemoteConfig(jg *graphv1beta1.Janusgraph) string {
+	config := fmt.Sprintf(`hosts: [%s]
+port: %d
+serializer:
+  className: org.apache.tinkerpop.gremlin.driver.ser.GryoMessageSerializerV3d0
+  config:
+    serializeResultToString: true
+`, gremlinServiceHost(jg), gremlinServicePort(jg))
+	if jg.Spec.TLS != nil {
+		config += `connectionPool:
+  enableSsl: true
+  sslSkipCertValidation: true
+`
+	}
+	if jg.Spec.Auth != nil && jg.Spec.Auth.Type == authTypeKerberos {
+		config += fmt.Sprintf("jaasEntry: %s\nprotocol: %s\n", kerberosJaasEntry, kerberosServiceName(jg.Spec.Auth.Principal))
+	}
+	return config
+}
+
+// gremlinScriptConfigMap returns the ConfigMap holding the files of a Gremlin script Job: the driver
+// configuration, runner.groovy run by the Gremlin Console and any scripts the runner reads
+func gremlinScriptConfigMap(name string, jg *graphv1beta1.Janusgraph, files map[string]string) *corev1.ConfigMap {
+	data := map[string]string{"remote.yaml": gremlinRemoteConfig(jg)}
+	if jg.Spec.Auth != nil && jg.Spec.Auth.Type == authTypeKerberos {
+		data["jaas.conf"] = kerberosJaasConfig(jg)
+	}
+	for file, content := range files {
+		data[file] = content
+	}
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: jg.Namespace,
+			Labels:    labelsForJanusgraph(jg.Name),
+		},
+		Data: data,
+	}
+}
+
+// gremlinScriptJob returns a Job that runs runner.groovy of the given ConfigMap against a JanusGraph object.
+// Callers may add volumes and environment variables for the runner. The Job uses the JanusGraph
+// image of the instance so the Gremlin Console matches the server version.
+func gremlinScriptJob(name string, jg *graphv1beta1.Janusgraph, configMapName string, timeout time.Duration) *batchv1.Job {
+	backoffLimit := int32(2)
+	authEnv, authMounts, authVolumes := gremlinClientSecurity(jg)
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: jg.Namespace,
+			Labels:    labelsForJanusgraph(jg.Name),
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{
+						{
+							Name:    gremlinContainerName,
+							Image:   imageForJanusgraph(jg),
+							Command: []string{"bin/gremlin.sh", "-e", gremlinScriptsPath + "/runner.groovy"},
+							Env: []corev1.EnvVar{
+								{
+									Name:  "SCRIPT_TIMEOUT_MS",
+									Value: strconv.FormatInt(timeout.Milliseconds(), 10),
+								},
+							},
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      "scripts",
+									MountPath: gremlinScriptsPath,
+								},
+							},
+							TerminationMessagePolicy: corev1.TerminationMessageReadFile,
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: "scripts",
+							VolumeSource: corev1.VolumeSource{
+								ConfigMap: &corev1.ConfigMapVolumeSource{
+									LocalObjectReference: corev1.LocalObjectReference{Name: configMapName},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	container := &job.Spec.Template.Spec.Containers[0]
+	container.Env = append(container.Env, authEnv...)
+	container.VolumeMounts = append(container.VolumeMounts, authMounts...)
+	job.Spec.Template.Spec.Volumes = append(job.Spec.Template.Spec.Volumes, authVolumes...)
+	return job
+}
+
+// boundedName returns name when it has at most maxLength characters. A longer name is cut and suffixed
+// with a hash of the whole name, so names sharing a long prefix stay distinct.
+func boundedName(name string, maxLength int) string {
+	if len(name) <= maxLength {
+		return name
+	}
+	hash := fnv.New32a()
+	hash.Write([]byte(name))
+	suffix := fmt.Sprintf("-%08x", hash.Sum32())
+	return strings.TrimRight(name[:maxLength-len(suffix)], "-.") + suffix
+}
+
+// jobFinished reports whether the Job has completed and whether it failed
+func jobFinished(job *batchv1.Job) (finished bool, failed bool) {
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			return true, false
+		case batchv1.JobFailed:
+			return true, true
+		}
+	}
+	return false, false
+}
+
+// gremlinJobOutput returns the termination message written by the Gremlin Console in the most recently
+// finished pod of a Gremlin script Job: the result of the script on success, or the error that stopped it.
+// The Gremlin Console may run as an init container when other containers of the Job depend on its result.
+func gremlinJobOutput(ctx context.Context, c client.Client, job *batchv1.Job) (string, error) {
+	podList := &corev1.PodList{}
+	listOpts := []client.ListOption{
+		client.InNamespace(job.Namespace),
+		client.MatchingLabels{"job-name": job.Name},
+	}
+	if err := c.List(ctx, podList, listOpts...); err != nil {
+		return "", err
+	}
+	var output string
+	var finishedAt time.Time
+	for _, pod := range podList.Items {
+		statuses := append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...)
+		statuses = append(statuses, pod.Status.ContainerStatuses...)
+		for _, status := range statuses {
+			terminated := status.State.Terminated
+			if status.Name == gremlinContainerName && terminated != nil && !terminated.FinishedAt.Time.Before(finishedAt) {
+				output = terminated.Message
+				finishedAt = terminated.FinishedAt.Time
+			}
+		}
+	}
+	return output, nil
+}
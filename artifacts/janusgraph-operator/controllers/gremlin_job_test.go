@@ -0,0 +1,43 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBoundedName(t *testing.T) {
+	if name := boundedName("schema-sample-schema-3", maxJobNameLength); name != "schema-sample-schema-3" {
+		t.Errorf("boundedName changed the short name schema-sample-schema-3 to %s", name)
+	}
+
+	long := strings.Repeat("a", 60)
+	first := boundedName(long+"-schema-1", maxJobNameLength)
+	second := boundedName(long+"-schema-2", maxJobNameLength)
+	for _, name := range []string{first, second} {
+		if len(name) > maxJobNameLength {
+			t.Errorf("boundedName returned %s, longer than %d characters", name, maxJobNameLength)
+		}
+		if !strings.HasPrefix(name, long[:50]) {
+			t.Errorf("boundedName returned %s, which does not start with the name it was given", name)
+		}
+	}
+	if first == second {
+		t.Errorf("boundedName returned %s for two different names", first)
+	}
+}
@@ -0,0 +1,937 @@
+/*
+Copyright 2021.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	graphv1beta1 "github.com/example/janusgraph-operator/api/v1beta1"
+	"github.com/example/operator-common/owned"
+)
+
+// JanusgraphReconciler reconciles a Janusgraph object
+type JanusgraphReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+
+	// Recorder records Events on Janusgraph objects, e.g. when it is scaled or upgraded.
+	// It defaults to a recorder of the manager.
+	Recorder record.EventRecorder
+
+	// APIReader reads the Janusgraph from the API server rather than the cache before its status is written.
+	// It defaults to the API reader of the manager.
+	APIReader client.Reader
+
+	// objects creates and updates the Services, ConfigMaps and StatefulSet of a Janusgraph
+	objects *owned.Reconciler
+}
+
+// +kubebuilder:rbac:groups=graph.example.com,resources=janusgraphs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=graph.example.com,resources=janusgraphs/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=graph.example.com,resources=janusgraphs/finalizers,verbs=update
+// +kubebuilder:rbac:groups=apps,resources=pods;deployments;statefulsets;services;persistentvolumeclaims;persistentvolumes;,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=pods;services;persistentvolumeclaims;persistentvolumes;,verbs=get;list;create;update;patch;watch
+// +kubebuilder:rbac:groups=core,resources=persistentvolumeclaims,verbs=delete
+// +kubebuilder:rbac:groups=graph.example.com,resources=janusgraphbackups,verbs=get;list;watch;create
+// +kubebuilder:rbac:groups=core,resources=pods/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=monitoring.coreos.com,resources=servicemonitors,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+
+// Reasons of the Events recorded on a Janusgraph, next to the ones of the owned package
+const (
+	reasonDraining      = "Draining"
+	reasonDrainTimeout  = "DrainTimeout"
+	reasonUpgrading     = "Upgrading"
+	reasonConfigChanged = "ConfigChanged"
+	reasonRollingUpdate = "RollingUpdate"
+	reasonRolloutHalted = "RolloutHalted"
+	reasonUpgraded      = "Upgraded"
+)
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+// TODO(user): Modify the Reconcile function to compare the state specified by
+// the Janusgraph object against the actual cluster state, and then
+// perform operations to make the cluster state reflect the state specified by
+// the user.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.7.0/pkg/reconcile
+func (r *JanusgraphReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("janusgraph", req.NamespacedName)
+
+	// Fetch the Janusgraph instance
+	janusgraph := &graphv1beta1.Janusgraph{}
+	err := r.Get(ctx, req.NamespacedName, janusgraph)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// Request object not found, could have been deleted after reconcile request.
+			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
+			// Return and don't requeue
+			log.Info("Janusgraph resource not found. Ignoring since object must be deleted")
+			return ctrl.Result{}, nil
+		}
+		// Error reading the object - requeue the request.
+		log.Error(err, "Failed to get Janusgraph")
+		return ctrl.Result{}, err
+	}
+
+	//a deleted Janusgraph keeps running until its cleanup has finished, see finalizeJanusgraph
+	if janusgraph.DeletionTimestamp != nil {
+		return r.finalizeJanusgraph(ctx, janusgraph)
+	}
+	if !controllerutil.ContainsFinalizer(janusgraph, janusgraphFinalizer) {
+		controllerutil.AddFinalizer(janusgraph, janusgraphFinalizer)
+		if err = r.Update(ctx, janusgraph); err != nil {
+			log.Error(err, "Failed to add finalizer")
+			r.Recorder.Eventf(janusgraph, corev1.EventTypeWarning, owned.ReasonFailedUpdate, "Failed to add finalizer: %v", err)
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	//the Services come first, the headless Service governs the StatefulSet and gives every pod a stable DNS name.
+	//The JMX exporter sidecar reads its configuration from a ConfigMap and serves metrics on a dedicated Service;
+	//those objects are deleted again when Spec.Metrics is unset.
+	//The StatefulSet is only created here, scaling, storage and rolling updates are handled below.
+	statefulSetDep := r.statefulSetForJanusgraph(janusgraph)
+	objects := []owned.Object{
+		{Desired: r.serviceForJanusgraph(janusgraph), Merge: mergeService},
+		{Desired: r.headlessServiceForJanusgraph(janusgraph), Merge: mergeService},
+	}
+	objects = append(objects, r.metricsObjects(janusgraph)...)
+	objects = append(objects, owned.Object{Desired: statefulSetDep})
+
+	//Reconcile returns nil once every object exists and matches the spec
+	result, err := r.objects.Reconcile(ctx, janusgraph, objects...)
+	if result != nil {
+		return *result, err
+	}
+
+	found := &appsv1.StatefulSet{}
+	err = r.Get(ctx, types.NamespacedName{Name: janusgraph.Name, Namespace: janusgraph.Namespace}, found)
+	if err != nil {
+		log.Error(err, "Failed to get StatefulSet")
+		return ctrl.Result{}, err
+	}
+	//take the pods a scale down removes out of the Services, and put every other pod in
+	waiting, err := r.ensurePodsServing(ctx, janusgraph, found)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Ensure the statefulset's replicas are the same as defined in the spec section of the custom resource
+	size := janusgraph.Spec.Replicas
+	if *found.Spec.Replicas > size {
+		//the pods being removed are drained first, see scaleDown
+		return r.scaleDown(ctx, janusgraph, found)
+	}
+	if *found.Spec.Replicas < size {
+		current := *found.Spec.Replicas
+		found.Spec.Replicas = &size
+		err = r.Update(ctx, found)
+		if err != nil {
+			log.Error(err, "Failed to update Deployment", "Deployment.Namespace", found.Namespace, "Deployment.Name", found.Name)
+			r.Recorder.Eventf(janusgraph, corev1.EventTypeWarning, owned.ReasonFailedScale, "Failed to scale StatefulSet %s to %d replicas: %v", found.Name, size, err)
+			return ctrl.Result{}, err
+		}
+		r.Recorder.Eventf(janusgraph, corev1.EventTypeNormal, owned.ReasonScaled, "Scaled StatefulSet %s from %d to %d replicas", found.Name, current, size)
+		// Spec updated - return and requeue
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	//ensureStorage expands the data volumes when Spec.Storage.Size grows
+	result, err = r.ensureStorage(ctx, janusgraph, found, statefulSetDep)
+	if result != nil {
+		return *result, err
+	}
+
+	//ensureStatefulSetTemplate starts or advances a rolling update when the pod template has drifted,
+	//e.g. after Spec.Image.Version was changed, and returns nil once every pod runs the desired template
+	result, err = r.ensureStatefulSetTemplate(ctx, janusgraph, found, statefulSetDep)
+	if err != nil {
+		return *result, err
+	}
+
+	//the status is also updated while a rolling update is in progress, so it shows the upgrade
+	if err = r.updateStatus(ctx, janusgraph, found, result != nil); err != nil {
+		return ctrl.Result{}, err
+	}
+	if result != nil {
+		return *result, nil
+	}
+	//pods that are still being created need their serving readiness gate once they exist
+	if waiting {
+		return ctrl.Result{RequeueAfter: upgradeRequeueDelay}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// readyPods returns the pods whose readiness probe passed
+func readyPods(pods []corev1.Pod) []corev1.Pod {
+	var ready []corev1.Pod
+	for i := range pods {
+		if podReady(&pods[i]) {
+			ready = append(ready, pods[i])
+		}
+	}
+	return ready
+}
+
+// SetupWithManager sets up the controller with the Manager.
+// Changes to the owned StatefulSet, Services and ConfigMaps, and pods becoming ready or failing,
+// trigger a reconcile of the Janusgraph, so deleted objects are recreated and the status stays current.
+func (r *JanusgraphReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Recorder == nil {
+		r.Recorder = mgr.GetEventRecorderFor("janusgraph-controller")
+	}
+	if r.APIReader == nil {
+		r.APIReader = mgr.GetAPIReader()
+	}
+	r.objects = &owned.Reconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: r.Recorder,
+		Log:      r.Log,
+	}
+	//periodic resyncs replay objects that did not change
+	changed := builder.WithPredicates(predicate.ResourceVersionChangedPredicate{})
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&graphv1beta1.Janusgraph{}).
+		Owns(&appsv1.StatefulSet{}, changed).
+		Owns(&corev1.Service{}, changed).
+		Owns(&corev1.ConfigMap{}, changed).
+		Watches(&source.Kind{Type: &corev1.Pod{}},
+			handler.EnqueueRequestsFromMapFunc(janusgraphForPod),
+			builder.WithPredicates(podStatusChanged)).
+		Complete(r)
+}
+
+// janusgraphForPod maps a JanusGraph pod to the Janusgraph it belongs to, using the labels of labelsForJanusgraph.
+// The pods are owned by the StatefulSet rather than the Janusgraph, so Owns cannot be used for them.
+func janusgraphForPod(obj client.Object) []reconcile.Request {
+	labels := obj.GetLabels()
+	if labels["app"] != "Janusgraph" || labels["janusgraph_cr"] == "" {
+		return nil
+	}
+	return []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Name: labels["janusgraph_cr"], Namespace: obj.GetNamespace()}},
+	}
+}
+
+// podStatusChanged filters pod updates down to the changes the reconcile acts on: readiness, which is
+// reported in the status, and the revision and start failures of pods, which drive rolling updates.
+// Pods being created or deleted always trigger a reconcile.
+var podStatusChanged = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldPod, ok := e.ObjectOld.(*corev1.Pod)
+		if !ok {
+			return true
+		}
+		newPod, ok := e.ObjectNew.(*corev1.Pod)
+		if !ok {
+			return true
+		}
+		return podReady(oldPod) != podReady(newPod) ||
+			oldPod.Labels[appsv1.StatefulSetRevisionLabel] != newPod.Labels[appsv1.StatefulSetRevisionLabel] ||
+			podFailureReason(oldPod) != podFailureReason(newPod)
+	},
+}
+
+// labelsForJanusgraph returns a map of string keys and string values
+func labelsForJanusgraph(name string) map[string]string {
+	return map[string]string{"app": "Janusgraph", "janusgraph_cr": name}
+}
+
+// gremlinPort is the port the Gremlin Server listens on inside the JanusGraph container
+const gremlinPort = 8182
+
+// defaultProbeQuery is the Gremlin query submitted by the probes when Spec.Probes.Query is empty.
+// g is only bound once the graph has been opened, so the query fails while JanusGraph is starting.
+const defaultProbeQuery = "g.inject(1)"
+
+// dataVolumeName is the name of the volumeClaimTemplate holding the data of a JanusGraph pod.
+// Its claims are named data-<name>-<ordinal>.
+const dataVolumeName = "data"
+
+// janusgraphDataPath is where the JanusGraph image keeps its BerkeleyDB and Lucene data
+const janusgraphDataPath = "/var/lib/janusgraph"
+
+// upgradeRequeueDelay is how long to wait before checking on the pod currently being upgraded
+const upgradeRequeueDelay = 10 * time.Second
+
+// serviceForJanusgraph returns the client facing Service for our JanusGraph object.
+// The Service type, port, nodePort and load balancer settings come from Spec.Service
+// and default to a ClusterIP Service on port 8182.
+func (r *JanusgraphReconciler) serviceForJanusgraph(m *graphv1beta1.Janusgraph) *corev1.Service {
+
+	//fetch labels
+	ls := labelsForJanusgraph(m.Name)
+
+	serviceType := m.Spec.Service.Type
+	if serviceType == "" {
+		serviceType = corev1.ServiceTypeClusterIP
+	}
+	servicePort := corev1.ServicePort{
+		Name:       "gremlin",
+		Port:       gremlinServicePort(m),
+		TargetPort: intstr.FromInt(gremlinPort),
+	}
+	// a nodePort is only valid for NodePort and LoadBalancer Services, leaving it
+	// empty lets the cluster pick a free one
+	if serviceType != corev1.ServiceTypeClusterIP {
+		servicePort.NodePort = m.Spec.Service.NodePort
+	}
+
+	//create Service
+	srv := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        m.Name + "-service",
+			Namespace:   m.Namespace,
+			Annotations: withManagedAnnotations(m.Spec.Service.Annotations),
+		},
+		Spec: corev1.ServiceSpec{
+			Type:     serviceType,
+			Ports:    []corev1.ServicePort{servicePort},
+			Selector: ls,
+		},
+	}
+	if serviceType == corev1.ServiceTypeLoadBalancer {
+		srv.Spec.LoadBalancerSourceRanges = m.Spec.Service.LoadBalancerSourceRanges
+	}
+	return srv
+}
+
+// imageForJanusgraph returns the JanusGraph container image of a JanusGraph object.
+// The defaults are applied here too, for objects created before the defaulting webhook was installed.
+func imageForJanusgraph(m *graphv1beta1.Janusgraph) string {
+	image, version := m.Spec.Image.Repository, m.Spec.Image.Version
+	if image == "" {
+		image = graphv1beta1.DefaultJanusgraphImage
+	}
+	if version == "" {
+		version = graphv1beta1.DefaultJanusgraphVersion
+	}
+	return image + ":" + version
+}
+
+// gremlinServiceHost returns the in-cluster DNS name of the client facing Service of a JanusGraph object
+func gremlinServiceHost(m *graphv1beta1.Janusgraph) string {
+	return m.Name + "-service." + m.Namespace + ".svc"
+}
+
+// gremlinServicePort returns the port Gremlin clients use on the client facing Service
+func gremlinServicePort(m *graphv1beta1.Janusgraph) int32 {
+	if m.Spec.Service.Port == 0 {
+		return gremlinPort
+	}
+	return m.Spec.Service.Port
+}
+
+// headlessServiceName returns the name of the governing Service of the JanusGraph StatefulSet
+func headlessServiceName(m *graphv1beta1.Janusgraph) string {
+	return m.Name + "-headless"
+}
+
+// headlessServiceForJanusgraph returns the headless Service governing the JanusGraph StatefulSet.
+// It gives each pod a stable DNS name such as <name>-0.<name>-headless.<namespace>.svc,
+// so Gremlin clients and sidecars can address individual members.
+func (r *JanusgraphReconciler) headlessServiceForJanusgraph(m *graphv1beta1.Janusgraph) *corev1.Service {
+	ls := labelsForJanusgraph(m.Name)
+	srv := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      headlessServiceName(m),
+			Namespace: m.Namespace,
+		},
+		Spec: corev1.ServiceSpec{
+			// the type is set explicitly, the API server defaults it and mergeService compares it
+			Type:      corev1.ServiceTypeClusterIP,
+			ClusterIP: corev1.ClusterIPNone,
+			Ports: []corev1.ServicePort{
+				{
+					Name:       "gremlin",
+					Port:       gremlinPort,
+					TargetPort: intstr.FromInt(gremlinPort),
+				},
+			},
+			Selector: ls,
+			// publish DNS records while pods are starting so peers can find each other
+			PublishNotReadyAddresses: true,
+		},
+	}
+	return srv
+}
+
+// statefulSetForJanusgraph returns a StatefulSet for our JanusGraph object
+func (r *JanusgraphReconciler) statefulSetForJanusgraph(m *graphv1beta1.Janusgraph) *appsv1.StatefulSet {
+	//fetch labels
+	ls := labelsForJanusgraph(m.Name)
+	//fetch the size of the JanusGraph object from the custom resource
+	replicas := m.Spec.Replicas
+	//probe the Gremlin Server so pods only count as ready once the graph is open
+	startupProbe, readinessProbe, livenessProbe := probesForJanusgraph(m)
+	//auth and TLS are configured through the environment, with credentials and keystores read from Secrets
+	env, volumeMounts, volumes := gremlinServerSecurity(m)
+	if env == nil {
+		env = []corev1.EnvVar{}
+	}
+	//size the heap from the memory limit of the pod rather than the memory of the node
+	if javaOptions := javaOptionsForJanusgraph(m); javaOptions != "" {
+		env = append(env, corev1.EnvVar{Name: "JAVA_OPTIONS", Value: javaOptions})
+	}
+	//pods are upgraded from the highest ordinal down by lowering the partition, see rollStatefulSet
+	partition := int32(0)
+
+	//create StatefulSet
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      m.Name,
+			Namespace: m.Namespace,
+		},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: ls,
+			},
+			ServiceName: headlessServiceName(m),
+			UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
+				Type: appsv1.RollingUpdateStatefulSetStrategyType,
+				RollingUpdate: &appsv1.RollingUpdateStatefulSetStrategy{
+					Partition: &partition,
+				},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: ls,
+					Name:   "janusgraph",
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Image: imageForJanusgraph(m),
+							Name:  "janusgraph",
+							Ports: []corev1.ContainerPort{
+								{
+									ContainerPort: gremlinPort,
+									Name:          "janusgraph",
+								},
+							},
+							Env:            env,
+							Resources:      m.Spec.Resources,
+							VolumeMounts:   volumeMounts,
+							StartupProbe:   startupProbe,
+							ReadinessProbe: readinessProbe,
+							LivenessProbe:  livenessProbe,
+						}},
+					//pods only receive traffic while the operator keeps them serving, see ensurePodsServing
+					ReadinessGates: []corev1.PodReadinessGate{
+						{ConditionType: servingCondition},
+					},
+					Volumes:       volumes,
+					RestartPolicy: corev1.RestartPolicyAlways,
+				},
+			},
+		},
+	}
+	if m.Spec.Metrics != nil {
+		metricsEnv, exporter, exporterVolume := metricsForJanusgraph(m)
+		podSpec := &statefulSet.Spec.Template.Spec
+		podSpec.Containers[0].Env = append(podSpec.Containers[0].Env, metricsEnv...)
+		podSpec.Containers = append(podSpec.Containers, exporter)
+		podSpec.Volumes = append(podSpec.Volumes, exporterVolume)
+	}
+	if m.Spec.Storage != nil {
+		statefulSet.Spec.VolumeClaimTemplates = []corev1.PersistentVolumeClaim{claimTemplateForJanusgraph(m)}
+		setDataVolumeMount(&statefulSet.Spec.Template, true)
+	}
+	return statefulSet
+}
+
+// claimTemplateForJanusgraph returns the volumeClaimTemplate of the JanusGraph data volumes
+func claimTemplateForJanusgraph(m *graphv1beta1.Janusgraph) corev1.PersistentVolumeClaim {
+	accessModes := m.Spec.Storage.AccessModes
+	if len(accessModes) == 0 {
+		accessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+	}
+	claim := corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   dataVolumeName,
+			Labels: labelsForJanusgraph(m.Name),
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      accessModes,
+			StorageClassName: m.Spec.Storage.StorageClassName,
+		},
+	}
+	claim.Spec.Resources.Requests = corev1.ResourceList{
+		corev1.ResourceStorage: m.Spec.Storage.Size,
+	}
+	return claim
+}
+
+// setDataVolumeMount adds or removes the mount of the data volume in the JanusGraph container
+func setDataVolumeMount(template *corev1.PodTemplateSpec, mounted bool) {
+	container := &template.Spec.Containers[0]
+	var mounts []corev1.VolumeMount
+	for _, mount := range container.VolumeMounts {
+		if mount.Name != dataVolumeName {
+			mounts = append(mounts, mount)
+		}
+	}
+	if mounted {
+		mounts = append(mounts, corev1.VolumeMount{Name: dataVolumeName, MountPath: janusgraphDataPath})
+	}
+	container.VolumeMounts = mounts
+}
+
+// javaOptionsForJanusgraph returns the JAVA_OPTIONS of the JanusGraph container. The heap defaults to a
+// share of the memory limit; without a limit or an explicit heap size no heap flags are set.
+func javaOptionsForJanusgraph(m *graphv1beta1.Janusgraph) string {
+	jvm := m.Spec.JVM
+	var options []string
+
+	heapMax := jvm.HeapMax
+	if heapMax == nil {
+		if limit, ok := m.Spec.Resources.Limits[corev1.ResourceMemory]; ok {
+			percentage := int64(jvm.HeapPercentage)
+			if percentage == 0 {
+				percentage = 50
+			}
+			heapMax = resource.NewQuantity(limit.Value()*percentage/100, resource.BinarySI)
+		}
+	}
+	if heapMax != nil {
+		heapMin := jvm.HeapMin
+		if heapMin == nil {
+			heapMin = heapMax
+		}
+		options = append(options, fmt.Sprintf("-Xms%dm", heapMin.Value()/(1024*1024)), fmt.Sprintf("-Xmx%dm", heapMax.Value()/(1024*1024)))
+	}
+
+	if heapMax != nil || len(jvm.GCOptions) > 0 {
+		gcOptions := jvm.GCOptions
+		if len(gcOptions) == 0 {
+			gcOptions = []string{"-XX:+UseG1GC"}
+		}
+		options = append(options, gcOptions...)
+	}
+	if m.Spec.Auth != nil && m.Spec.Auth.Type == authTypeKerberos {
+		options = append(options, "-Djava.security.krb5.conf="+gremlinAuthPath+"/krb5.conf")
+	}
+	if m.Spec.Metrics != nil {
+		options = append(options, jmxJavaOptions...)
+	}
+	options = append(options, jvm.ExtraOptions...)
+	return strings.Join(options, " ")
+}
+
+// probesForJanusgraph returns the startup, readiness and liveness probes of the JanusGraph container.
+// Every field is set explicitly so the probes compare equal to the ones read back from the API server.
+// With Simple auth the query is sent by curl with the credentials from the environment of the container,
+// with Kerberos auth the probes can only check that the Gremlin Server accepts connections.
+func probesForJanusgraph(m *graphv1beta1.Janusgraph) (*corev1.Probe, *corev1.Probe, *corev1.Probe) {
+	spec := m.Spec.Probes
+	query := spec.Query
+	if query == "" {
+		query = defaultProbeQuery
+	}
+	timeout := spec.TimeoutSeconds
+	if timeout == 0 {
+		timeout = 5
+	}
+	period := spec.PeriodSeconds
+	if period == 0 {
+		period = 10
+	}
+	failureThreshold := spec.FailureThreshold
+	if failureThreshold == 0 {
+		failureThreshold = 3
+	}
+	startupTimeout := spec.StartupTimeoutSeconds
+	if startupTimeout == 0 {
+		startupTimeout = 300
+	}
+
+	gremlinProbe := func(failureThreshold int32) *corev1.Probe {
+		probe := &corev1.Probe{
+			TimeoutSeconds:   timeout,
+			PeriodSeconds:    period,
+			SuccessThreshold: 1,
+			FailureThreshold: failureThreshold,
+		}
+		path := "/?gremlin=" + url.QueryEscape(query)
+		switch {
+		case m.Spec.Auth != nil && m.Spec.Auth.Type == authTypeKerberos:
+			probe.TCPSocket = &corev1.TCPSocketAction{
+				Port: intstr.FromInt(gremlinPort),
+			}
+		case m.Spec.Auth != nil:
+			probe.Exec = &corev1.ExecAction{
+				Command: []string{"sh", "-c", fmt.Sprintf(`curl -sfk -o /dev/null -u "$GREMLIN_USERNAME:$GREMLIN_PASSWORD" '%s://localhost:%d%s'`,
+					gremlinScheme(m), gremlinPort, path)},
+			}
+		default:
+			scheme := corev1.URISchemeHTTP
+			if m.Spec.TLS != nil {
+				scheme = corev1.URISchemeHTTPS
+			}
+			probe.HTTPGet = &corev1.HTTPGetAction{
+				Path:   path,
+				Port:   intstr.FromInt(gremlinPort),
+				Scheme: scheme,
+			}
+		}
+		return probe
+	}
+	//the startup probe holds back the other probes until the graph has been opened,
+	//allowing up to startupTimeout seconds before the container is restarted
+	startup := gremlinProbe((startupTimeout + period - 1) / period)
+	return startup, gremlinProbe(failureThreshold), gremlinProbe(failureThreshold)
+}
+
+//ensureStorage grows the data volumes of the JanusGraph pods to Spec.Storage.Size.
+//The volumeClaimTemplates of a StatefulSet cannot be changed, so the existing claims are patched instead;
+//claims of pods created later are expanded on a following reconcile. Volumes are never shrunk.
+//If storage was added to or removed from the spec after the StatefulSet was created, the desired pod
+//template keeps the volumes of the live StatefulSet and a warning is reported in the status.
+//ensureStorage returns nil, nil once every claim has at least the requested size
+func (r *JanusgraphReconciler) ensureStorage(ctx context.Context, janusgraph *graphv1beta1.Janusgraph,
+	found *appsv1.StatefulSet, desired *appsv1.StatefulSet) (*ctrl.Result, error) {
+	log := r.Log.WithValues("janusgraph", types.NamespacedName{Name: janusgraph.Name, Namespace: janusgraph.Namespace})
+	if storageWarning(janusgraph, found) != "" {
+		desired.Spec.VolumeClaimTemplates = found.Spec.VolumeClaimTemplates
+		setDataVolumeMount(&desired.Spec.Template, hasDataClaimTemplate(found))
+		return nil, nil
+	}
+	if janusgraph.Spec.Storage == nil {
+		return nil, nil
+	}
+	size := janusgraph.Spec.Storage.Size
+
+	claimList := &corev1.PersistentVolumeClaimList{}
+	listOpts := []client.ListOption{
+		client.InNamespace(janusgraph.Namespace),
+		client.MatchingLabels(labelsForJanusgraph(janusgraph.Name)),
+	}
+	if err := r.List(ctx, claimList, listOpts...); err != nil {
+		log.Error(err, "Failed to list PersistentVolumeClaims")
+		return &ctrl.Result{}, err
+	}
+	for i := range claimList.Items {
+		claim := &claimList.Items[i]
+		if _, ok := podOrdinal(claim.Name, dataVolumeName+"-"+found.Name); !ok {
+			continue
+		}
+		current := claim.Spec.Resources.Requests[corev1.ResourceStorage]
+		if size.Cmp(current) <= 0 {
+			continue
+		}
+		log.Info("Expanding PersistentVolumeClaim", "PersistentVolumeClaim.Namespace", claim.Namespace, "PersistentVolumeClaim.Name", claim.Name, "Size", size.String())
+		updated := claim.DeepCopy()
+		updated.Spec.Resources.Requests[corev1.ResourceStorage] = size
+		if err := r.Patch(ctx, updated, client.MergeFrom(claim)); err != nil {
+			//expansion is rejected when the StorageClass does not allow it
+			log.Error(err, "Failed to expand PersistentVolumeClaim", "PersistentVolumeClaim.Namespace", claim.Namespace, "PersistentVolumeClaim.Name", claim.Name)
+			r.Recorder.Eventf(janusgraph, corev1.EventTypeWarning, owned.ReasonFailedUpdate, "Failed to expand PersistentVolumeClaim %s: %v", claim.Name, err)
+			return &ctrl.Result{}, err
+		}
+		r.Recorder.Eventf(janusgraph, corev1.EventTypeNormal, owned.ReasonUpdated, "Expanded PersistentVolumeClaim %s to %s", claim.Name, size.String())
+	}
+	return nil, nil
+}
+
+//storageWarning returns why Spec.Storage cannot be applied to the live StatefulSet, or an empty string
+func storageWarning(m *graphv1beta1.Janusgraph, found *appsv1.StatefulSet) string {
+	switch {
+	case m.Spec.Storage != nil && !hasDataClaimTemplate(found):
+		return "storage cannot be added to an existing Janusgraph, the pods keep running without persistent volumes"
+	case m.Spec.Storage == nil && hasDataClaimTemplate(found):
+		return "storage cannot be removed from an existing Janusgraph, the pods keep their persistent volumes"
+	}
+	return ""
+}
+
+//hasDataClaimTemplate reports whether the StatefulSet has the volumeClaimTemplate of the data volumes
+func hasDataClaimTemplate(statefulSet *appsv1.StatefulSet) bool {
+	for _, claim := range statefulSet.Spec.VolumeClaimTemplates {
+		if claim.Name == dataVolumeName {
+			return true
+		}
+	}
+	return false
+}
+
+//ensureStatefulSetTemplate compares the pod template of the live StatefulSet with the desired one.
+//When the image, env, resources, probes or volumes have drifted it writes the desired template and sets the
+//rolling update partition to the highest ordinal, so only that pod is replaced at first.
+//ensureStatefulSetTemplate returns nil, nil once the rolling update has reached every pod
+func (r *JanusgraphReconciler) ensureStatefulSetTemplate(ctx context.Context, janusgraph *graphv1beta1.Janusgraph,
+	found *appsv1.StatefulSet, desired *appsv1.StatefulSet) (*ctrl.Result, error) {
+	log := r.Log.WithValues("janusgraph", types.NamespacedName{Name: janusgraph.Name, Namespace: janusgraph.Namespace})
+	if !podTemplateDrifted(&found.Spec.Template, &desired.Spec.Template) {
+		return r.rollStatefulSet(ctx, janusgraph, found)
+	}
+
+	partition := *found.Spec.Replicas - 1
+	if partition < 0 {
+		partition = 0
+	}
+	log.Info("Pod template changed, starting rolling update", "StatefulSet.Namespace", found.Namespace, "StatefulSet.Name", found.Name, "Partition", partition)
+	updated := found.DeepCopy()
+	updated.Spec.Template = desired.Spec.Template
+	updated.Spec.UpdateStrategy = appsv1.StatefulSetUpdateStrategy{
+		Type: appsv1.RollingUpdateStatefulSetStrategyType,
+		RollingUpdate: &appsv1.RollingUpdateStatefulSetStrategy{
+			Partition: &partition,
+		},
+	}
+	err := r.Patch(ctx, updated, client.MergeFrom(found))
+	if err != nil {
+		log.Error(err, "Failed to update StatefulSet", "StatefulSet.Namespace", found.Namespace, "StatefulSet.Name", found.Name)
+		r.Recorder.Eventf(janusgraph, corev1.EventTypeWarning, owned.ReasonFailedUpdate, "Failed to update StatefulSet %s: %v", found.Name, err)
+		return &ctrl.Result{}, err
+	}
+	//a new image is an upgrade, any other change of the template a configuration rollout
+	liveImage := found.Spec.Template.Spec.Containers[0].Image
+	desiredImage := desired.Spec.Template.Spec.Containers[0].Image
+	if liveImage != desiredImage {
+		r.Recorder.Eventf(janusgraph, corev1.EventTypeNormal, reasonUpgrading, "Upgrading StatefulSet %s from %s to %s", found.Name, liveImage, desiredImage)
+	} else {
+		r.Recorder.Eventf(janusgraph, corev1.EventTypeNormal, reasonConfigChanged, "Rolling out the changed pod template of StatefulSet %s", found.Name)
+	}
+	return &ctrl.Result{RequeueAfter: upgradeRequeueDelay}, nil
+}
+
+//rollStatefulSet advances a partitioned rolling update by one ordinal at a time.
+//The partition is only lowered once every pod at or above it runs the update revision and is ready.
+//If one of those pods fails to start the rollout halts, leaving the remaining pods on the old version.
+//rollStatefulSet returns nil, nil when no rolling update is in progress
+func (r *JanusgraphReconciler) rollStatefulSet(ctx context.Context, janusgraph *graphv1beta1.Janusgraph,
+	found *appsv1.StatefulSet) (*ctrl.Result, error) {
+	log := r.Log.WithValues("janusgraph", types.NamespacedName{Name: janusgraph.Name, Namespace: janusgraph.Namespace})
+	rollingUpdate := found.Spec.UpdateStrategy.RollingUpdate
+	if rollingUpdate == nil || rollingUpdate.Partition == nil || *rollingUpdate.Partition == 0 {
+		return nil, nil
+	}
+	//wait for the StatefulSet controller to observe the new template before judging the pods
+	if found.Status.ObservedGeneration < found.Generation {
+		return &ctrl.Result{RequeueAfter: upgradeRequeueDelay}, nil
+	}
+	partition := *rollingUpdate.Partition
+
+	podList := &corev1.PodList{}
+	listOpts := []client.ListOption{
+		client.InNamespace(janusgraph.Namespace),
+		client.MatchingLabels(labelsForJanusgraph(janusgraph.Name)),
+	}
+	if err := r.List(ctx, podList, listOpts...); err != nil {
+		log.Error(err, "Failed to list pods")
+		return &ctrl.Result{}, err
+	}
+	for _, pod := range podList.Items {
+		ordinal, ok := podOrdinal(pod.Name, found.Name)
+		if !ok || ordinal < partition || ordinal >= *found.Spec.Replicas {
+			continue
+		}
+		if reason := podFailureReason(&pod); reason != "" {
+			log.Info("Rolling update halted, pod failed to start", "Pod.Name", pod.Name, "Reason", reason, "Partition", partition)
+			r.Recorder.Eventf(janusgraph, corev1.EventTypeWarning, reasonRolloutHalted, "Rolling update halted, pod %s failed to start: %s", pod.Name, reason)
+			return &ctrl.Result{RequeueAfter: upgradeRequeueDelay}, nil
+		}
+		if pod.Labels[appsv1.StatefulSetRevisionLabel] != found.Status.UpdateRevision || !podReady(&pod) {
+			//pod is still being replaced or opening the graph
+			return &ctrl.Result{RequeueAfter: upgradeRequeueDelay}, nil
+		}
+	}
+
+	partition--
+	log.Info("Advancing rolling update", "StatefulSet.Namespace", found.Namespace, "StatefulSet.Name", found.Name, "Partition", partition)
+	updated := found.DeepCopy()
+	updated.Spec.UpdateStrategy.RollingUpdate.Partition = &partition
+	if err := r.Patch(ctx, updated, client.MergeFrom(found)); err != nil {
+		log.Error(err, "Failed to update StatefulSet", "StatefulSet.Namespace", found.Namespace, "StatefulSet.Name", found.Name)
+		r.Recorder.Eventf(janusgraph, corev1.EventTypeWarning, owned.ReasonFailedUpdate, "Failed to update StatefulSet %s: %v", found.Name, err)
+		return &ctrl.Result{}, err
+	}
+	r.Recorder.Eventf(janusgraph, corev1.EventTypeNormal, reasonRollingUpdate, "Updating pod %s-%d", found.Name, partition)
+	return &ctrl.Result{RequeueAfter: upgradeRequeueDelay}, nil
+}
+
+//podTemplateDrifted reports whether the fields the operator manages differ between the live and desired pod templates.
+//Fields defaulted by the API server are ignored so an unchanged spec never triggers a rollout.
+func podTemplateDrifted(live, desired *corev1.PodTemplateSpec) bool {
+	if !equality.Semantic.DeepEqual(live.Labels, desired.Labels) {
+		return true
+	}
+	//the readiness gates are not compared, so StatefulSets created before the serving gate existed are not
+	//rolled just to add it; they get the gate with the next rolling update for any other change
+	if !equality.Semantic.DeepEqual(live.Spec.Volumes, desired.Spec.Volumes) {
+		return true
+	}
+	if len(live.Spec.Containers) != len(desired.Spec.Containers) {
+		return true
+	}
+	for i := range desired.Spec.Containers {
+		l, d := live.Spec.Containers[i], desired.Spec.Containers[i]
+		if l.Name != d.Name ||
+			l.Image != d.Image ||
+			!equality.Semantic.DeepEqual(l.Command, d.Command) ||
+			!equality.Semantic.DeepEqual(l.Env, d.Env) ||
+			!equality.Semantic.DeepEqual(l.VolumeMounts, d.VolumeMounts) ||
+			!equality.Semantic.DeepEqual(l.Resources, d.Resources) ||
+			!equality.Semantic.DeepEqual(l.StartupProbe, d.StartupProbe) ||
+			!equality.Semantic.DeepEqual(l.ReadinessProbe, d.ReadinessProbe) ||
+			!equality.Semantic.DeepEqual(l.LivenessProbe, d.LivenessProbe) {
+			return true
+		}
+	}
+	return false
+}
+
+//podOrdinal returns the ordinal of a StatefulSet pod, e.g. 2 for janusgraph-2
+func podOrdinal(podName string, statefulSetName string) (int32, bool) {
+	if !strings.HasPrefix(podName, statefulSetName+"-") {
+		return 0, false
+	}
+	ordinal, err := strconv.ParseInt(strings.TrimPrefix(podName, statefulSetName+"-"), 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return int32(ordinal), true
+}
+
+//podReady reports whether the Ready condition of the pod is true
+func podReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+//podFailureReason returns why a container of the pod cannot start, or an empty string if it is not failing
+func podFailureReason(pod *corev1.Pod) string {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Waiting == nil {
+			continue
+		}
+		switch status.State.Waiting.Reason {
+		case "CrashLoopBackOff", "ErrImagePull", "ImagePullBackOff", "InvalidImageName", "CreateContainerConfigError":
+			return status.State.Waiting.Reason
+		}
+	}
+	return ""
+}
+
+//managedAnnotationsKey is the annotation of the client Service listing the keys of the annotations copied
+//from spec.service.annotations, so the ones later removed from the spec can be told from those of others
+const managedAnnotationsKey = "graph.example.com/managed-annotations"
+
+//withManagedAnnotations returns a copy of the annotations with managedAnnotationsKey listing their keys
+func withManagedAnnotations(annotations map[string]string) map[string]string {
+	if len(annotations) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(annotations))
+	managed := make(map[string]string, len(annotations)+1)
+	for key, value := range annotations {
+		keys = append(keys, key)
+		managed[key] = value
+	}
+	sort.Strings(keys)
+	managed[managedAnnotationsKey] = strings.Join(keys, ",")
+	return managed
+}
+
+//mergeService copies the fields the operator manages from the desired Service into the live one.
+//Fields assigned by the cluster, such as clusterIP and nodePorts that were not
+//explicitly requested, are preserved so the patch does not try to change them.
+func mergeService(liveObj, desiredObj client.Object) {
+	live, desired := liveObj.(*corev1.Service), desiredObj.(*corev1.Service)
+	ports := make([]corev1.ServicePort, len(desired.Spec.Ports))
+	for i, port := range desired.Spec.Ports {
+		ports[i] = port
+		if ports[i].Protocol == "" {
+			ports[i].Protocol = corev1.ProtocolTCP
+		}
+		//keep an auto-assigned nodePort unless the Service no longer uses one
+		if port.NodePort == 0 && desired.Spec.Type != corev1.ServiceTypeClusterIP {
+			for _, livePort := range live.Spec.Ports {
+				if livePort.Name == port.Name {
+					ports[i].NodePort = livePort.NodePort
+				}
+			}
+		}
+	}
+	live.Spec.Ports = ports
+	//an empty type is defaulted by the API server, copying it would patch the type on every reconcile
+	if desired.Spec.Type != "" {
+		live.Spec.Type = desired.Spec.Type
+	}
+	live.Spec.Selector = desired.Spec.Selector
+	live.Spec.LoadBalancerSourceRanges = desired.Spec.LoadBalancerSourceRanges
+	live.Spec.PublishNotReadyAddresses = desired.Spec.PublishNotReadyAddresses
+	//external traffic settings are rejected on ClusterIP Services
+	if desired.Spec.Type == corev1.ServiceTypeClusterIP {
+		live.Spec.ExternalTrafficPolicy = ""
+		live.Spec.HealthCheckNodePort = 0
+	}
+
+	//annotations set by other controllers are left alone, ours are added or overwritten, and the ones
+	//recorded by the previous merge that are no longer in the spec are removed
+	if previous, ok := live.Annotations[managedAnnotationsKey]; ok {
+		for _, key := range append(strings.Split(previous, ","), managedAnnotationsKey) {
+			if _, ok := desired.Annotations[key]; !ok {
+				delete(live.Annotations, key)
+			}
+		}
+	}
+	if len(desired.Annotations) > 0 && live.Annotations == nil {
+		live.Annotations = map[string]string{}
+	}
+	for key, value := range desired.Annotations {
+		live.Annotations[key] = value
+	}
+}
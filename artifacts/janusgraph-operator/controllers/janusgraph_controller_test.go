@@ -0,0 +1,198 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	graphv1beta1 "github.com/example/janusgraph-operator/api/v1beta1"
+)
+
+var _ = Describe("Janusgraph controller", func() {
+	const (
+		timeout  = 10 * time.Second
+		interval = 250 * time.Millisecond
+	)
+
+	var (
+		ctx       context.Context
+		namespace string
+		key       types.NamespacedName
+	)
+
+	// every test runs in its own namespace, envtest has no namespace controller to delete them
+	BeforeEach(func() {
+		ctx = context.Background()
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "janusgraph-test-"}}
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+		namespace = ns.Name
+		key = types.NamespacedName{Name: "janusgraph-sample", Namespace: namespace}
+
+		janusgraph := &graphv1beta1.Janusgraph{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+			Spec: graphv1beta1.JanusgraphSpec{
+				Replicas: 1,
+				Image: graphv1beta1.JanusgraphImageSpec{
+					Repository: graphv1beta1.DefaultJanusgraphImage,
+					Version:    "0.5.3",
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, janusgraph)).To(Succeed())
+	})
+
+	getJanusgraph := func() (*graphv1beta1.Janusgraph, error) {
+		janusgraph := &graphv1beta1.Janusgraph{}
+		err := k8sClient.Get(ctx, key, janusgraph)
+		return janusgraph, err
+	}
+
+	getStatefulSet := func() (*appsv1.StatefulSet, error) {
+		statefulSet := &appsv1.StatefulSet{}
+		err := k8sClient.Get(ctx, key, statefulSet)
+		return statefulSet, err
+	}
+
+	// expectOwned checks that the Janusgraph is the controller of the object
+	expectOwned := func(obj client.Object) {
+		janusgraph, err := getJanusgraph()
+		Expect(err).NotTo(HaveOccurred())
+		owner := metav1.GetControllerOf(obj)
+		Expect(owner).NotTo(BeNil())
+		Expect(owner.Kind).To(Equal("Janusgraph"))
+		Expect(owner.UID).To(Equal(janusgraph.UID))
+	}
+
+	It("creates the Services and the StatefulSet owned by the Janusgraph", func() {
+		var statefulSet *appsv1.StatefulSet
+		Eventually(func() error {
+			var err error
+			statefulSet, err = getStatefulSet()
+			return err
+		}, timeout, interval).Should(Succeed())
+		Expect(*statefulSet.Spec.Replicas).To(Equal(int32(1)))
+		Expect(statefulSet.Spec.ServiceName).To(Equal(key.Name + "-headless"))
+		Expect(statefulSet.Spec.Template.Spec.Containers[0].Image).To(Equal(graphv1beta1.DefaultJanusgraphImage + ":0.5.3"))
+		expectOwned(statefulSet)
+
+		service := &corev1.Service{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: key.Name + "-service", Namespace: namespace}, service)).To(Succeed())
+		Expect(service.Spec.Type).To(Equal(corev1.ServiceTypeClusterIP))
+		Expect(service.Spec.Selector).To(Equal(labelsForJanusgraph(key.Name)))
+		expectOwned(service)
+
+		headless := &corev1.Service{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: key.Name + "-headless", Namespace: namespace}, headless)).To(Succeed())
+		Expect(headless.Spec.ClusterIP).To(Equal(corev1.ClusterIPNone))
+		expectOwned(headless)
+
+		janusgraph, err := getJanusgraph()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(janusgraph.Finalizers).To(ContainElement(janusgraphFinalizer))
+	})
+
+	It("scales the StatefulSet up with the replicas of the Janusgraph", func() {
+		Eventually(getStatefulSet, timeout, interval).ShouldNot(BeNil())
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			janusgraph, err := getJanusgraph()
+			if err != nil {
+				return err
+			}
+			janusgraph.Spec.Replicas = 3
+			return k8sClient.Update(ctx, janusgraph)
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func() int32 {
+			statefulSet, err := getStatefulSet()
+			if err != nil {
+				return 0
+			}
+			return *statefulSet.Spec.Replicas
+		}, timeout, interval).Should(Equal(int32(3)))
+	})
+
+	It("lists the ready JanusGraph pods in status.nodes", func() {
+		Eventually(getStatefulSet, timeout, interval).ShouldNot(BeNil())
+		// envtest runs no StatefulSet controller or kubelet, so the test creates the pod and marks it ready
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name + "-0", Namespace: namespace, Labels: labelsForJanusgraph(key.Name)},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "janusgraph", Image: graphv1beta1.DefaultJanusgraphImage + ":0.5.3"}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(pod), pod); err != nil {
+				return err
+			}
+			pod.Status.Conditions = append(pod.Status.Conditions, corev1.PodCondition{
+				Type:               corev1.PodReady,
+				Status:             corev1.ConditionTrue,
+				LastTransitionTime: metav1.Now(),
+			})
+			return k8sClient.Status().Update(ctx, pod)
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func() []string {
+			janusgraph, err := getJanusgraph()
+			if err != nil {
+				return nil
+			}
+			return janusgraph.Status.Nodes
+		}, timeout, interval).Should(ConsistOf(key.Name + "-0"))
+
+		// the pod kept by the replicas is put in the Services by its serving readiness gate
+		Eventually(func() corev1.ConditionStatus {
+			if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(pod), pod); err != nil {
+				return ""
+			}
+			return podCondition(pod, servingCondition)
+		}, timeout, interval).Should(Equal(corev1.ConditionTrue))
+	})
+
+	It("releases a deleted Janusgraph once its cleanup has run", func() {
+		Eventually(func() []string {
+			janusgraph, err := getJanusgraph()
+			if err != nil {
+				return nil
+			}
+			return janusgraph.Finalizers
+		}, timeout, interval).Should(ContainElement(janusgraphFinalizer))
+
+		janusgraph, err := getJanusgraph()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(k8sClient.Delete(ctx, janusgraph)).To(Succeed())
+
+		Eventually(func() bool {
+			_, err := getJanusgraph()
+			return errors.IsNotFound(err)
+		}, timeout, interval).Should(BeTrue())
+	})
+})
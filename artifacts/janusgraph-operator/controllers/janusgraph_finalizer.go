@@ -0,0 +1,212 @@
+/*
+Copyright 2021.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	graphv1alpha1 "github.com/example/janusgraph-operator/api/v1alpha1"
+	graphv1beta1 "github.com/example/janusgraph-operator/api/v1beta1"
+	"github.com/example/operator-common/owned"
+)
+
+// janusgraphFinalizer holds a deleted Janusgraph until its cleanup has run
+const janusgraphFinalizer = "graph.example.com/finalizer"
+
+const (
+	cleanupPhaseRunning  = "Running"
+	cleanupPhaseFailed   = "Failed"
+	cleanupPhaseTimedOut = "TimedOut"
+)
+
+// defaultCleanupTimeout is how long the cleanup may take when Spec.Cleanup.TimeoutSeconds is not set
+const defaultCleanupTimeout = 30 * time.Minute
+
+// cleanupRequeueDelay is how often a cleanup in progress is checked
+const cleanupRequeueDelay = 10 * time.Second
+
+// reasonFailedCleanup is the reason of the Event recorded when the cleanup of a deleted Janusgraph failed or timed out
+const reasonFailedCleanup = "FailedCleanup"
+
+//finalizeJanusgraph runs the cleanup of a deleted Janusgraph and then removes its finalizer.
+//It takes the final backup and waits for it, then deletes the data volumes if the policy says so.
+//If the cleanup does not finish within the timeout the finalizer is removed anyway, so a failing
+//backup cannot block the deletion forever; the state of the cleanup is recorded in Status.Cleanup.
+func (r *JanusgraphReconciler) finalizeJanusgraph(ctx context.Context, janusgraph *graphv1beta1.Janusgraph) (ctrl.Result, error) {
+	log := r.Log.WithValues("janusgraph", types.NamespacedName{Name: janusgraph.Name, Namespace: janusgraph.Namespace})
+	if !controllerutil.ContainsFinalizer(janusgraph, janusgraphFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	timeout := defaultCleanupTimeout
+	if janusgraph.Spec.Cleanup.TimeoutSeconds != 0 {
+		timeout = time.Duration(janusgraph.Spec.Cleanup.TimeoutSeconds) * time.Second
+	}
+	if time.Since(janusgraph.DeletionTimestamp.Time) > timeout {
+		message := "cleanup did not finish in time"
+		if janusgraph.Status.Cleanup != nil && janusgraph.Status.Cleanup.Message != "" {
+			message += ": " + janusgraph.Status.Cleanup.Message
+		}
+		log.Info("Cleanup timed out, releasing Janusgraph")
+		if err := r.setCleanupPhase(ctx, janusgraph, cleanupPhaseTimedOut, message); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, r.removeFinalizer(ctx, janusgraph)
+	}
+
+	if storage := janusgraph.Spec.Cleanup.FinalBackup; storage != nil {
+		backup, err := r.ensureFinalBackup(ctx, janusgraph, storage)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		switch backup.Status.Phase {
+		case backupPhaseSucceeded:
+		case backupPhaseFailed:
+			//the backup is not retried, the deletion waits for the timeout so the failure can be noticed
+			err = r.setCleanupPhase(ctx, janusgraph, cleanupPhaseFailed, "final backup "+backup.Name+" failed: "+backup.Status.Message)
+			return ctrl.Result{RequeueAfter: cleanupRequeueDelay}, err
+		default:
+			err = r.setCleanupPhase(ctx, janusgraph, cleanupPhaseRunning, "waiting for final backup "+backup.Name)
+			return ctrl.Result{RequeueAfter: cleanupRequeueDelay}, err
+		}
+	}
+
+	if janusgraph.Spec.Cleanup.VolumeClaimPolicy == "Delete" {
+		if err := r.deleteDataVolumes(ctx, janusgraph); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	log.Info("Cleanup finished, releasing Janusgraph")
+	return ctrl.Result{}, r.removeFinalizer(ctx, janusgraph)
+}
+
+//finalBackupName returns the name of the final backup of a Janusgraph, suffixed with a hash of its uid.
+//The hash has a fixed length whatever the uid looks like, even an empty one.
+func finalBackupName(janusgraph *graphv1beta1.Janusgraph) string {
+	hash := fnv.New32a()
+	hash.Write([]byte(janusgraph.UID))
+	return fmt.Sprintf("%s-final-%08x", janusgraph.Name, hash.Sum32())
+}
+
+//ensureFinalBackup returns the final backup of a deleted Janusgraph, creating it if it does not exist.
+//The backup is not owned by the Janusgraph, so it is kept after the Janusgraph is gone. Its name
+//includes the uid of the Janusgraph so a Janusgraph created again with the same name takes its own.
+func (r *JanusgraphReconciler) ensureFinalBackup(ctx context.Context, janusgraph *graphv1beta1.Janusgraph,
+	storage *graphv1beta1.BackupStorage) (*graphv1alpha1.JanusgraphBackup, error) {
+	log := r.Log.WithValues("janusgraph", types.NamespacedName{Name: janusgraph.Name, Namespace: janusgraph.Namespace})
+	name := finalBackupName(janusgraph)
+	backup := &graphv1alpha1.JanusgraphBackup{}
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: janusgraph.Namespace}, backup)
+	if err != nil && errors.IsNotFound(err) {
+		backup = &graphv1alpha1.JanusgraphBackup{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: janusgraph.Namespace,
+			},
+			Spec: graphv1alpha1.JanusgraphBackupSpec{
+				JanusgraphRef: janusgraph.Name,
+				Storage: graphv1alpha1.BackupStorage{
+					PersistentVolumeClaim: (*graphv1alpha1.PersistentVolumeClaimStorage)(storage.PersistentVolumeClaim),
+					S3:                    (*graphv1alpha1.S3Storage)(storage.S3),
+				},
+			},
+		}
+		log.Info("Creating final backup", "JanusgraphBackup.Namespace", backup.Namespace, "JanusgraphBackup.Name", backup.Name)
+		if err = r.Create(ctx, backup); err != nil {
+			log.Error(err, "Failed to create final backup", "JanusgraphBackup.Namespace", backup.Namespace, "JanusgraphBackup.Name", backup.Name)
+			r.Recorder.Eventf(janusgraph, corev1.EventTypeWarning, owned.ReasonFailedCreate, "Failed to create final backup %s: %v", backup.Name, err)
+			return nil, err
+		}
+		r.Recorder.Eventf(janusgraph, corev1.EventTypeNormal, owned.ReasonCreated, "Created final backup %s", backup.Name)
+		return backup, nil
+	} else if err != nil {
+		log.Error(err, "Failed to get final backup")
+		return nil, err
+	}
+	return backup, nil
+}
+
+//deleteDataVolumes deletes the PersistentVolumeClaims of the data volumes of a Janusgraph.
+//They are only removed once the pods using them are gone.
+func (r *JanusgraphReconciler) deleteDataVolumes(ctx context.Context, janusgraph *graphv1beta1.Janusgraph) error {
+	log := r.Log.WithValues("janusgraph", types.NamespacedName{Name: janusgraph.Name, Namespace: janusgraph.Namespace})
+	claimList := &corev1.PersistentVolumeClaimList{}
+	listOpts := []client.ListOption{
+		client.InNamespace(janusgraph.Namespace),
+		client.MatchingLabels(labelsForJanusgraph(janusgraph.Name)),
+	}
+	if err := r.List(ctx, claimList, listOpts...); err != nil {
+		log.Error(err, "Failed to list PersistentVolumeClaims")
+		return err
+	}
+	for i := range claimList.Items {
+		claim := &claimList.Items[i]
+		if _, ok := podOrdinal(claim.Name, dataVolumeName+"-"+janusgraph.Name); !ok {
+			continue
+		}
+		log.Info("Deleting PersistentVolumeClaim", "PersistentVolumeClaim.Namespace", claim.Namespace, "PersistentVolumeClaim.Name", claim.Name)
+		if err := r.Delete(ctx, claim); err != nil && !errors.IsNotFound(err) {
+			log.Error(err, "Failed to delete PersistentVolumeClaim", "PersistentVolumeClaim.Namespace", claim.Namespace, "PersistentVolumeClaim.Name", claim.Name)
+			r.Recorder.Eventf(janusgraph, corev1.EventTypeWarning, owned.ReasonFailedDelete, "Failed to delete PersistentVolumeClaim %s: %v", claim.Name, err)
+			return err
+		}
+		r.Recorder.Eventf(janusgraph, corev1.EventTypeNormal, owned.ReasonDeleted, "Deleted PersistentVolumeClaim %s", claim.Name)
+	}
+	return nil
+}
+
+//setCleanupPhase records the phase and message of the cleanup of a deleted Janusgraph
+func (r *JanusgraphReconciler) setCleanupPhase(ctx context.Context, janusgraph *graphv1beta1.Janusgraph, phase string, message string) error {
+	log := r.Log.WithValues("janusgraph", types.NamespacedName{Name: janusgraph.Name, Namespace: janusgraph.Namespace})
+	cleanup := &graphv1beta1.JanusgraphCleanupStatus{Phase: phase, Message: message}
+	if janusgraph.Status.Cleanup != nil && *janusgraph.Status.Cleanup == *cleanup {
+		return nil
+	}
+	patch := client.MergeFrom(janusgraph.DeepCopy())
+	janusgraph.Status.Cleanup = cleanup
+	if err := r.Status().Patch(ctx, janusgraph, patch); err != nil {
+		log.Error(err, "Failed to update Janusgraph status")
+		r.Recorder.Eventf(janusgraph, corev1.EventTypeWarning, owned.ReasonFailedUpdateStatus, "Failed to update status: %v", err)
+		return err
+	}
+	if phase != cleanupPhaseRunning {
+		r.Recorder.Eventf(janusgraph, corev1.EventTypeWarning, reasonFailedCleanup, "Cleanup %s: %s", strings.ToLower(phase), message)
+	}
+	return nil
+}
+
+//removeFinalizer releases a deleted Janusgraph, letting the API server delete it and its owned objects
+func (r *JanusgraphReconciler) removeFinalizer(ctx context.Context, janusgraph *graphv1beta1.Janusgraph) error {
+	log := r.Log.WithValues("janusgraph", types.NamespacedName{Name: janusgraph.Name, Namespace: janusgraph.Namespace})
+	controllerutil.RemoveFinalizer(janusgraph, janusgraphFinalizer)
+	if err := r.Update(ctx, janusgraph); err != nil {
+		log.Error(err, "Failed to remove finalizer")
+		r.Recorder.Eventf(janusgraph, corev1.EventTypeWarning, owned.ReasonFailedUpdate, "Failed to remove finalizer: %v", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+/*
+Copyright 2021.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	graphv1beta1 "github.com/example/janusgraph-operator/api/v1beta1"
+	"github.com/example/operator-common/owned"
+)
+
+// defaultMetricsPort is the port the JMX exporter serves metrics on when Spec.Metrics.Port is empty
+const defaultMetricsPort = 9404
+
+// defaultExporterImage is the JMX exporter image used when Spec.Metrics.ExporterImage is empty
+const defaultExporterImage = "bitnami/jmx-exporter:0.17.0"
+
+// jmxPort is the port of the JMX connector of the JanusGraph JVM. It is bound to the loopback
+// interface, so only the exporter sidecar in the same pod can reach it.
+const jmxPort = 9999
+
+// jmxExporterPath is where the configuration of the JMX exporter is mounted
+const jmxExporterPath = "/etc/jmx-exporter"
+
+// configFileMode is the mode of the files projected from ConfigMaps. It is set explicitly so the
+// volumes compare equal to the ones read back from the API server.
+var configFileMode = int32(0644)
+
+// jmxJavaOptions open the JMX connector of the JanusGraph JVM for the exporter sidecar
+var jmxJavaOptions = []string{
+	"-Dcom.sun.management.jmxremote",
+	"-Dcom.sun.management.jmxremote.host=127.0.0.1",
+	"-Djava.rmi.server.hostname=127.0.0.1",
+	"-Dcom.sun.management.jmxremote.port=" + strconv.Itoa(jmxPort),
+	"-Dcom.sun.management.jmxremote.rmi.port=" + strconv.Itoa(jmxPort),
+	"-Dcom.sun.management.jmxremote.authenticate=false",
+	"-Dcom.sun.management.jmxremote.ssl=false",
+}
+
+// jmxExporterConfig is the configuration of the JMX exporter. It exports every MBean of the JanusGraph JVM:
+// the Gremlin Server and JanusGraph metrics in the metrics domain and the JVM metrics in java.lang.
+var jmxExporterConfig = fmt.Sprintf(`hostPort: 127.0.0.1:%d
+lowercaseOutputName: true
+lowercaseOutputLabelNames: true
+rules:
+  - pattern: ".*"
+`, jmxPort)
+
+// metricsPort returns the port the JMX exporter of a JanusGraph object serves metrics on
+func metricsPort(m *graphv1beta1.Janusgraph) int32 {
+	if m.Spec.Metrics == nil || m.Spec.Metrics.Port == 0 {
+		return defaultMetricsPort
+	}
+	return m.Spec.Metrics.Port
+}
+
+// labelsForMetrics returns the labels of the metrics Service, selected by the ServiceMonitor
+func labelsForMetrics(name string) map[string]string {
+	return map[string]string{"app": "Janusgraph-metrics", "janusgraph_cr": name}
+}
+
+// metricsForJanusgraph returns the environment enabling the JMX reporter of the Gremlin Server, and the
+// JMX exporter sidecar with its volume
+func metricsForJanusgraph(m *graphv1beta1.Janusgraph) ([]corev1.EnvVar, corev1.Container, corev1.Volume) {
+	image := m.Spec.Metrics.ExporterImage
+	if image == "" {
+		image = defaultExporterImage
+	}
+	env := []corev1.EnvVar{
+		{Name: "gremlinserver.metrics.jmxReporter.enabled", Value: "true"},
+	}
+	exporter := corev1.Container{
+		Name:  "jmx-exporter",
+		Image: image,
+		Args:  []string{strconv.Itoa(int(metricsPort(m))), jmxExporterPath + "/config.yaml"},
+		Ports: []corev1.ContainerPort{
+			{
+				ContainerPort: metricsPort(m),
+				Name:          "metrics",
+			},
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      "jmx-exporter",
+				MountPath: jmxExporterPath,
+				ReadOnly:  true,
+			},
+		},
+	}
+	volume := corev1.Volume{
+		Name: "jmx-exporter",
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: m.Name + "-jmx-exporter"},
+				DefaultMode:          &configFileMode,
+			},
+		},
+	}
+	return env, exporter, volume
+}
+
+// exporterConfigMapForJanusgraph returns the ConfigMap holding the configuration of the JMX exporter
+func (r *JanusgraphReconciler) exporterConfigMapForJanusgraph(m *graphv1beta1.Janusgraph) *corev1.ConfigMap {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      m.Name + "-jmx-exporter",
+			Namespace: m.Namespace,
+			Labels:    labelsForJanusgraph(m.Name),
+		},
+		Data: map[string]string{"config.yaml": jmxExporterConfig},
+	}
+	return configMap
+}
+
+// metricsServiceForJanusgraph returns the Service exposing the metrics port of every JanusGraph pod
+func (r *JanusgraphReconciler) metricsServiceForJanusgraph(m *graphv1beta1.Janusgraph) *corev1.Service {
+	srv := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      m.Name + "-metrics",
+			Namespace: m.Namespace,
+			Labels:    labelsForMetrics(m.Name),
+		},
+		Spec: corev1.ServiceSpec{
+			Type: corev1.ServiceTypeClusterIP,
+			Ports: []corev1.ServicePort{
+				{
+					Name:       "metrics",
+					Port:       metricsPort(m),
+					TargetPort: intstr.FromInt(int(metricsPort(m))),
+				},
+			},
+			Selector: labelsForJanusgraph(m.Name),
+		},
+	}
+	return srv
+}
+
+// serviceMonitorForJanusgraph returns a Prometheus Operator ServiceMonitor scraping the metrics Service.
+// It is built as an unstructured object so the operator does not depend on the Prometheus Operator API.
+func (r *JanusgraphReconciler) serviceMonitorForJanusgraph(m *graphv1beta1.Janusgraph) *unstructured.Unstructured {
+	spec := graphv1beta1.ServiceMonitorSpec{}
+	if m.Spec.Metrics != nil && m.Spec.Metrics.ServiceMonitor != nil {
+		spec = *m.Spec.Metrics.ServiceMonitor
+	}
+	interval := spec.Interval
+	if interval == "" {
+		interval = "30s"
+	}
+	matchLabels := map[string]interface{}{}
+	for key, value := range labelsForMetrics(m.Name) {
+		matchLabels[key] = value
+	}
+
+	monitor := &unstructured.Unstructured{}
+	monitor.SetAPIVersion("monitoring.coreos.com/v1")
+	monitor.SetKind("ServiceMonitor")
+	monitor.SetName(m.Name)
+	monitor.SetNamespace(m.Namespace)
+	monitor.SetLabels(spec.Labels)
+	monitor.Object["spec"] = map[string]interface{}{
+		"selector": map[string]interface{}{
+			"matchLabels": matchLabels,
+		},
+		"endpoints": []interface{}{
+			map[string]interface{}{
+				"port":     "metrics",
+				"interval": interval,
+			},
+		},
+	}
+	return monitor
+}
+
+//metricsObjects returns the exporter ConfigMap, the metrics Service and the ServiceMonitor of a JanusGraph object.
+//When Spec.Metrics or its ServiceMonitor is unset the objects are returned as absent, so they are deleted.
+//The ServiceMonitor is skipped when its CRD is not installed, since the metrics Service still works without it.
+func (r *JanusgraphReconciler) metricsObjects(m *graphv1beta1.Janusgraph) []owned.Object {
+	metrics := m.Spec.Metrics
+	return []owned.Object{
+		{Desired: r.exporterConfigMapForJanusgraph(m), Merge: mergeConfigMap, Absent: metrics == nil},
+		{Desired: r.metricsServiceForJanusgraph(m), Merge: mergeService, Absent: metrics == nil},
+		{
+			Desired:  r.serviceMonitorForJanusgraph(m),
+			Merge:    mergeServiceMonitor,
+			Absent:   metrics == nil || metrics.ServiceMonitor == nil,
+			Optional: true,
+		},
+	}
+}
+
+//mergeConfigMap replaces the data of the live ConfigMap with the desired data
+func mergeConfigMap(live, desired client.Object) {
+	live.(*corev1.ConfigMap).Data = desired.(*corev1.ConfigMap).Data
+}
+
+//mergeServiceMonitor replaces the spec and labels of the live ServiceMonitor with the desired ones
+func mergeServiceMonitor(live, desired client.Object) {
+	liveMonitor, desiredMonitor := live.(*unstructured.Unstructured), desired.(*unstructured.Unstructured)
+	liveMonitor.Object["spec"] = desiredMonitor.Object["spec"]
+	liveMonitor.SetLabels(desiredMonitor.GetLabels())
+}
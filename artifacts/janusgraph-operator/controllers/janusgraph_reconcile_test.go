@@ -0,0 +1,273 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	graphv1alpha1 "github.com/example/janusgraph-operator/api/v1alpha1"
+	graphv1beta1 "github.com/example/janusgraph-operator/api/v1beta1"
+	"github.com/example/operator-common/owned"
+)
+
+// sampleJanusgraph returns the Janusgraph the tests of this file reconcile
+func sampleJanusgraph() *graphv1beta1.Janusgraph {
+	return &graphv1beta1.Janusgraph{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "janusgraph-sample",
+			Namespace:  "default",
+			Finalizers: []string{janusgraphFinalizer},
+		},
+		Spec: graphv1beta1.JanusgraphSpec{
+			Replicas: 1,
+			Image: graphv1beta1.JanusgraphImageSpec{
+				Repository: graphv1beta1.DefaultJanusgraphImage,
+				Version:    "0.5.3",
+			},
+		},
+	}
+}
+
+// sampleJanusgraphWithStorage returns the sample Janusgraph exposed by a LoadBalancer and keeping its data on volumes
+func sampleJanusgraphWithStorage() *graphv1beta1.Janusgraph {
+	janusgraph := sampleJanusgraph()
+	janusgraph.Spec.Replicas = 3
+	janusgraph.Spec.Service = graphv1beta1.JanusgraphServiceSpec{
+		Type:                     corev1.ServiceTypeLoadBalancer,
+		Port:                     443,
+		NodePort:                 30182,
+		LoadBalancerSourceRanges: []string{"10.0.0.0/8"},
+		Annotations:              map[string]string{"service.beta.kubernetes.io/aws-load-balancer-internal": "true"},
+	}
+	storageClassName := "standard"
+	janusgraph.Spec.Storage = &graphv1beta1.JanusgraphStorageSpec{
+		Size:             resource.MustParse("10Gi"),
+		StorageClassName: &storageClassName,
+	}
+	janusgraph.Spec.Resources = corev1.ResourceRequirements{
+		Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("2Gi")},
+	}
+	return janusgraph
+}
+
+func TestServiceForJanusgraphGolden(t *testing.T) {
+	r := &JanusgraphReconciler{}
+	expectGolden(t, "service_for_janusgraph", r.serviceForJanusgraph(sampleJanusgraph()))
+	expectGolden(t, "service_for_janusgraph_load_balancer", r.serviceForJanusgraph(sampleJanusgraphWithStorage()))
+}
+
+func TestMergeServiceRemovesStaleAnnotations(t *testing.T) {
+	r := &JanusgraphReconciler{}
+	janusgraph := sampleJanusgraph()
+	janusgraph.Spec.Service.Annotations = map[string]string{"example.com/a": "1", "example.com/b": "2"}
+	live := r.serviceForJanusgraph(janusgraph)
+	live.Annotations["example.com/other-controller"] = "kept"
+
+	janusgraph.Spec.Service.Annotations = map[string]string{"example.com/b": "3"}
+	mergeService(live, r.serviceForJanusgraph(janusgraph))
+	want := map[string]string{
+		"example.com/b":                "3",
+		"example.com/other-controller": "kept",
+		managedAnnotationsKey:          "example.com/b",
+	}
+	if !reflect.DeepEqual(live.Annotations, want) {
+		t.Errorf("merged annotations are %v, want %v", live.Annotations, want)
+	}
+
+	janusgraph.Spec.Service.Annotations = nil
+	mergeService(live, r.serviceForJanusgraph(janusgraph))
+	want = map[string]string{"example.com/other-controller": "kept"}
+	if !reflect.DeepEqual(live.Annotations, want) {
+		t.Errorf("merged annotations are %v, want %v", live.Annotations, want)
+	}
+}
+
+func TestHeadlessServiceForJanusgraphGolden(t *testing.T) {
+	r := &JanusgraphReconciler{}
+	expectGolden(t, "headless_service_for_janusgraph", r.headlessServiceForJanusgraph(sampleJanusgraph()))
+}
+
+func TestStatefulSetForJanusgraphGolden(t *testing.T) {
+	r := &JanusgraphReconciler{}
+	expectGolden(t, "stateful_set_for_janusgraph", r.statefulSetForJanusgraph(sampleJanusgraph()))
+	expectGolden(t, "stateful_set_for_janusgraph_storage", r.statefulSetForJanusgraph(sampleJanusgraphWithStorage()))
+}
+
+// errorClient fails the Get, Create or status Patch of a client with the given errors
+type errorClient struct {
+	client.Client
+	getErr    error
+	createErr error
+	statusErr error
+}
+
+func (c *errorClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	if c.getErr != nil {
+		return c.getErr
+	}
+	return c.Client.Get(ctx, key, obj)
+}
+
+func (c *errorClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if c.createErr != nil {
+		return c.createErr
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func (c *errorClient) Status() client.StatusWriter {
+	return &errorStatusWriter{StatusWriter: c.Client.Status(), err: c.statusErr}
+}
+
+// errorStatusWriter fails the status Patch of a Janusgraph with the given error
+type errorStatusWriter struct {
+	client.StatusWriter
+	err error
+}
+
+func (w *errorStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if _, ok := obj.(*graphv1beta1.Janusgraph); ok && w.err != nil {
+		return w.err
+	}
+	return w.StatusWriter.Patch(ctx, obj, patch, opts...)
+}
+
+// newTestJanusgraphReconciler returns a JanusgraphReconciler on a fake client holding the given objects.
+// The Get, Create and status Patch of the returned client fail with the errors set on it.
+func newTestJanusgraphReconciler(t *testing.T, objs ...client.Object) (*JanusgraphReconciler, *errorClient, *record.FakeRecorder) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := graphv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := graphv1beta1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	errs := &errorClient{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()}
+	recorder := record.NewFakeRecorder(100)
+	log := ctrl.Log.WithName("controllers").WithName("Janusgraph")
+	r := &JanusgraphReconciler{Client: errs, Log: log, Scheme: scheme, Recorder: recorder}
+	r.objects = &owned.Reconciler{Client: errs, Scheme: scheme, Recorder: recorder, Log: log}
+	return r, errs, recorder
+}
+
+// expectEvent checks that an Event with the given reason was recorded
+func expectEvent(t *testing.T, recorder *record.FakeRecorder, reason string) {
+	t.Helper()
+	for {
+		select {
+		case event := <-recorder.Events:
+			if strings.Contains(event, " "+reason+" ") {
+				return
+			}
+		default:
+			t.Errorf("No %s event was recorded", reason)
+			return
+		}
+	}
+}
+
+var janusgraphRequest = ctrl.Request{NamespacedName: types.NamespacedName{Name: "janusgraph-sample", Namespace: "default"}}
+
+func TestJanusgraphReconcileNotFound(t *testing.T) {
+	r, _, _ := newTestJanusgraphReconciler(t)
+	result, err := r.Reconcile(context.Background(), janusgraphRequest)
+	if err != nil {
+		t.Fatalf("Reconcile of a deleted Janusgraph failed: %v", err)
+	}
+	if result != (ctrl.Result{}) {
+		t.Errorf("Reconcile of a deleted Janusgraph returned %+v, want no requeue", result)
+	}
+}
+
+func TestJanusgraphReconcileGetError(t *testing.T) {
+	r, errs, _ := newTestJanusgraphReconciler(t, sampleJanusgraph())
+	errs.getErr = fmt.Errorf("connection refused")
+	if _, err := r.Reconcile(context.Background(), janusgraphRequest); err != errs.getErr {
+		t.Errorf("Reconcile returned %v, want the Get error %v", err, errs.getErr)
+	}
+}
+
+func TestJanusgraphReconcileCreateError(t *testing.T) {
+	r, errs, recorder := newTestJanusgraphReconciler(t, sampleJanusgraph())
+	errs.createErr = errors.NewForbidden(corev1.Resource("services"), "janusgraph-sample-service", fmt.Errorf("quota exceeded"))
+	if _, err := r.Reconcile(context.Background(), janusgraphRequest); err != errs.createErr {
+		t.Errorf("Reconcile returned %v, want the Create error %v", err, errs.createErr)
+	}
+	expectEvent(t, recorder, owned.ReasonFailedCreate)
+}
+
+func TestJanusgraphReconcileStatusPatchError(t *testing.T) {
+	janusgraph := sampleJanusgraph()
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "janusgraph-sample-0", Namespace: "default", Labels: labelsForJanusgraph(janusgraph.Name)},
+		Status: corev1.PodStatus{
+			Conditions: []corev1.PodCondition{
+				{Type: corev1.PodReady, Status: corev1.ConditionTrue},
+				{Type: servingCondition, Status: corev1.ConditionTrue},
+			},
+		},
+	}
+	r, errs, recorder := newTestJanusgraphReconciler(t, janusgraph, pod)
+
+	// create the Services and the StatefulSet and record the ready pod in the status
+	ctx := context.Background()
+	for i := 0; ; i++ {
+		result, err := r.Reconcile(ctx, janusgraphRequest)
+		if err != nil {
+			t.Fatalf("Reconcile failed: %v", err)
+		}
+		if !result.Requeue {
+			break
+		}
+		if i == 10 {
+			t.Fatal("Reconcile kept requeuing")
+		}
+	}
+
+	// the pod is no longer ready, so the status changes and its patch fails
+	if err := r.Get(ctx, client.ObjectKeyFromObject(pod), pod); err != nil {
+		t.Fatal(err)
+	}
+	pod.Status.Conditions[0].Status = corev1.ConditionFalse
+	if err := r.Status().Update(ctx, pod); err != nil {
+		t.Fatal(err)
+	}
+	errs.statusErr = errors.NewConflict(graphv1beta1.GroupVersion.WithResource("janusgraphs").GroupResource(), janusgraph.Name, fmt.Errorf("modified"))
+	if _, err := r.Reconcile(ctx, janusgraphRequest); err != errs.statusErr {
+		t.Errorf("Reconcile returned %v, want the status Patch error %v", err, errs.statusErr)
+	}
+	expectEvent(t, recorder, owned.ReasonFailedUpdateStatus)
+}
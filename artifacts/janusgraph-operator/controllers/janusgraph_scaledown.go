@@ -0,0 +1,267 @@
+/*
+Copyright 2021.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	graphv1beta1 "github.com/example/janusgraph-operator/api/v1beta1"
+	"github.com/example/operator-common/owned"
+)
+
+// servingCondition is the readiness gate of JanusGraph pods. The operator sets it to false on the pods
+// being removed by a scale down, so they leave the Services while they finish their transactions.
+const servingCondition corev1.PodConditionType = "graph.example.com/serving"
+
+// defaultDrainTimeout is how long pods are drained when Spec.ScaleDown.DrainTimeoutSeconds is not set
+const defaultDrainTimeout = 300 * time.Second
+
+// drainRequeueDelay is how often the open transactions of pods being drained are checked
+const drainRequeueDelay = 5 * time.Second
+
+// openTransactionsQuery returns the number of open transactions of a JanusGraph instance.
+// Sessions of Gremlin clients that are in a transaction are counted as well.
+const openTransactionsQuery = "graph.getOpenTransactions().size()"
+
+//ensurePodsServing sets the serving readiness gate of every JanusGraph pod: true for the pods kept by
+//Spec.Replicas and false for the pods a scale down is about to remove.
+//ensurePodsServing reports whether pods of the StatefulSet have not been created yet, and so still need their gate
+func (r *JanusgraphReconciler) ensurePodsServing(ctx context.Context, janusgraph *graphv1beta1.Janusgraph,
+	found *appsv1.StatefulSet) (bool, error) {
+	log := r.Log.WithValues("janusgraph", types.NamespacedName{Name: janusgraph.Name, Namespace: janusgraph.Namespace})
+	podList := &corev1.PodList{}
+	listOpts := []client.ListOption{
+		client.InNamespace(janusgraph.Namespace),
+		client.MatchingLabels(labelsForJanusgraph(janusgraph.Name)),
+	}
+	if err := r.List(ctx, podList, listOpts...); err != nil {
+		log.Error(err, "Failed to list pods")
+		return false, err
+	}
+
+	pods := int32(0)
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		ordinal, ok := podOrdinal(pod.Name, found.Name)
+		if !ok {
+			continue
+		}
+		pods++
+		condition := corev1.PodCondition{
+			Type:   servingCondition,
+			Status: corev1.ConditionTrue,
+			Reason: "Serving",
+		}
+		if ordinal >= janusgraph.Spec.Replicas {
+			condition.Status = corev1.ConditionFalse
+			condition.Reason = "Draining"
+		}
+		if podCondition(pod, servingCondition) == condition.Status {
+			continue
+		}
+
+		condition.LastTransitionTime = metav1.Now()
+		patch, err := servingConditionPatch(condition)
+		if err != nil {
+			return false, err
+		}
+		if err := r.Status().Patch(ctx, pod, patch); err != nil {
+			log.Error(err, "Failed to update pod status", "Pod.Namespace", pod.Namespace, "Pod.Name", pod.Name)
+			return false, err
+		}
+	}
+	return pods < *found.Spec.Replicas, nil
+}
+
+//scaleDown removes the pods above Spec.Replicas from the StatefulSet once they have been drained.
+//The pods are taken out of the Services by ensurePodsServing first, then scaleDown waits until they have
+//no open transactions left, or until the drain timeout, before it lowers the replicas of the StatefulSet.
+//The progress of the scale down is recorded in Status.ScaleDown.
+func (r *JanusgraphReconciler) scaleDown(ctx context.Context, janusgraph *graphv1beta1.Janusgraph,
+	found *appsv1.StatefulSet) (ctrl.Result, error) {
+	log := r.Log.WithValues("janusgraph", types.NamespacedName{Name: janusgraph.Name, Namespace: janusgraph.Namespace})
+	size := janusgraph.Spec.Replicas
+	state := janusgraph.Status.ScaleDown
+	if state == nil || state.Replicas != size {
+		var pods []string
+		for i := size; i < *found.Spec.Replicas; i++ {
+			pods = append(pods, fmt.Sprintf("%s-%d", found.Name, i))
+		}
+		log.Info("Draining pods before scaling down", "StatefulSet.Namespace", found.Namespace, "StatefulSet.Name", found.Name, "Pods", pods)
+		patch := client.MergeFrom(janusgraph.DeepCopy())
+		janusgraph.Status.ScaleDown = &graphv1beta1.JanusgraphScaleDownStatus{
+			Replicas:  size,
+			Pods:      pods,
+			StartTime: metav1.Now(),
+		}
+		if err := r.Status().Patch(ctx, janusgraph, patch); err != nil {
+			log.Error(err, "Failed to update Janusgraph status")
+			r.Recorder.Eventf(janusgraph, corev1.EventTypeWarning, owned.ReasonFailedUpdateStatus, "Failed to update status: %v", err)
+			return ctrl.Result{}, err
+		}
+		r.Recorder.Eventf(janusgraph, corev1.EventTypeNormal, reasonDraining, "Draining pods %s before scaling down to %d replicas", strings.Join(pods, ", "), size)
+		return ctrl.Result{RequeueAfter: drainRequeueDelay}, nil
+	}
+
+	timeout := defaultDrainTimeout
+	if janusgraph.Spec.ScaleDown.DrainTimeoutSeconds != nil {
+		timeout = time.Duration(*janusgraph.Spec.ScaleDown.DrainTimeoutSeconds) * time.Second
+	}
+	if time.Since(state.StartTime.Time) < timeout {
+		open, known := r.openTransactions(ctx, janusgraph, state.Pods)
+		if !known || open > 0 {
+			if state.OpenTransactions != open {
+				patch := client.MergeFrom(janusgraph.DeepCopy())
+				state.OpenTransactions = open
+				if err := r.Status().Patch(ctx, janusgraph, patch); err != nil {
+					log.Error(err, "Failed to update Janusgraph status")
+					return ctrl.Result{}, err
+				}
+			}
+			return ctrl.Result{RequeueAfter: drainRequeueDelay}, nil
+		}
+	} else {
+		log.Info("Drain timeout reached, scaling down with open transactions", "StatefulSet.Namespace", found.Namespace, "StatefulSet.Name", found.Name, "OpenTransactions", state.OpenTransactions)
+		r.Recorder.Eventf(janusgraph, corev1.EventTypeWarning, reasonDrainTimeout, "Drain timeout reached, scaling down with %d open transactions", state.OpenTransactions)
+	}
+
+	log.Info("Scaling down", "StatefulSet.Namespace", found.Namespace, "StatefulSet.Name", found.Name, "Replicas", size)
+	current := *found.Spec.Replicas
+	found.Spec.Replicas = &size
+	if err := r.Update(ctx, found); err != nil {
+		log.Error(err, "Failed to update StatefulSet", "StatefulSet.Namespace", found.Namespace, "StatefulSet.Name", found.Name)
+		r.Recorder.Eventf(janusgraph, corev1.EventTypeWarning, owned.ReasonFailedScale, "Failed to scale StatefulSet %s to %d replicas: %v", found.Name, size, err)
+		return ctrl.Result{}, err
+	}
+	r.Recorder.Eventf(janusgraph, corev1.EventTypeNormal, owned.ReasonScaled, "Scaled StatefulSet %s from %d to %d replicas", found.Name, current, size)
+	// Spec updated - return and requeue
+	return ctrl.Result{Requeue: true}, nil
+}
+
+//openTransactions returns the number of open transactions on the given pods, queried from their Gremlin Server
+//over HTTP. It reports false when the transactions cannot be queried, i.e. with Kerberos auth.
+//Pods that do not answer are counted as having no open transactions.
+func (r *JanusgraphReconciler) openTransactions(ctx context.Context, janusgraph *graphv1beta1.Janusgraph, pods []string) (int32, bool) {
+	log := r.Log.WithValues("janusgraph", types.NamespacedName{Name: janusgraph.Name, Namespace: janusgraph.Namespace})
+	if janusgraph.Spec.Auth != nil && janusgraph.Spec.Auth.Type == authTypeKerberos {
+		return 0, false
+	}
+	var username, password string
+	if janusgraph.Spec.Auth != nil {
+		secret := &corev1.Secret{}
+		err := r.Get(ctx, types.NamespacedName{Name: janusgraph.Spec.Auth.CredentialsSecret, Namespace: janusgraph.Namespace}, secret)
+		if err != nil {
+			log.Error(err, "Failed to get credentials Secret", "Secret.Namespace", janusgraph.Namespace, "Secret.Name", janusgraph.Spec.Auth.CredentialsSecret)
+			return 0, false
+		}
+		username, password = string(secret.Data["username"]), string(secret.Data["password"])
+	}
+
+	httpClient := &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			//the Gremlin Server certificate is issued for the Service, not the pod addresses
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+	open := int32(0)
+	for _, pod := range pods {
+		endpoint := fmt.Sprintf("%s://%s.%s.%s.svc:%d/?gremlin=%s", gremlinScheme(janusgraph), pod, headlessServiceName(janusgraph),
+			janusgraph.Namespace, gremlinPort, url.QueryEscape(openTransactionsQuery))
+		req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+		if err != nil {
+			return 0, false
+		}
+		if username != "" {
+			req.SetBasicAuth(username, password)
+		}
+		resp, err := httpClient.Do(req.WithContext(ctx))
+		if err != nil {
+			log.Info("Failed to query open transactions", "Pod.Name", pod, "Error", err.Error())
+			continue
+		}
+		response := struct {
+			Result struct {
+				Data interface{} `json:"data"`
+			} `json:"result"`
+		}{}
+		err = json.NewDecoder(resp.Body).Decode(&response)
+		resp.Body.Close()
+		if err != nil || resp.StatusCode != http.StatusOK {
+			log.Info("Failed to query open transactions", "Pod.Name", pod, "Status", resp.StatusCode)
+			continue
+		}
+		if count, ok := firstNumber(response.Result.Data); ok {
+			open += int32(count)
+		}
+	}
+	return open, true
+}
+
+//firstNumber returns the first number in a GraphSON result, e.g. 2 for [2] or
+//{"@type":"g:List","@value":[{"@type":"g:Int32","@value":2}]}
+func firstNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case []interface{}:
+		for _, item := range v {
+			if number, ok := firstNumber(item); ok {
+				return number, true
+			}
+		}
+	case map[string]interface{}:
+		return firstNumber(v["@value"])
+	}
+	return 0, false
+}
+
+//podCondition returns the status of a condition of the pod, or an empty string if the pod does not have it
+func podCondition(pod *corev1.Pod, conditionType corev1.PodConditionType) corev1.ConditionStatus {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == conditionType {
+			return condition.Status
+		}
+	}
+	return ""
+}
+
+//servingConditionPatch returns a strategic merge patch setting only the given condition of a pod.
+//The conditions of a pod are merged by type, so the conditions written by the kubelet since the pod
+//was read, such as Ready, are kept; a JSON merge patch would replace the whole list.
+func servingConditionPatch(condition corev1.PodCondition) (client.Patch, error) {
+	data, err := json.Marshal(map[string]interface{}{
+		"status": map[string]interface{}{
+			"conditions": []corev1.PodCondition{condition},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return client.RawPatch(types.StrategicMergePatchType, data), nil
+}
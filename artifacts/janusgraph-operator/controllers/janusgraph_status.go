@@ -0,0 +1,271 @@
+/*
+Copyright 2021.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	graphv1beta1 "github.com/example/janusgraph-operator/api/v1beta1"
+	"github.com/example/operator-common/owned"
+)
+
+// Condition types of a Janusgraph
+const (
+	// conditionStorageReady is true once the data volume of every pod is bound
+	conditionStorageReady = "StorageReady"
+	// conditionServiceReady is true once the client Service exists and, for a LoadBalancer, has an ingress
+	conditionServiceReady = "ServiceReady"
+	// conditionGremlinReady is true once the Gremlin Server of every pod answers queries
+	conditionGremlinReady = "GremlinReady"
+	// conditionUpgrading is true while a rolling update of the pods is in progress
+	conditionUpgrading = "Upgrading"
+)
+
+//updateStatus computes the status of a JanusGraph object from its StatefulSet, pods, volumes and client Service,
+//and writes it when it changed. upgrading reports whether a rolling update is in progress.
+func (r *JanusgraphReconciler) updateStatus(ctx context.Context, janusgraph *graphv1beta1.Janusgraph,
+	found *appsv1.StatefulSet, upgrading bool) error {
+	log := r.Log.WithValues("janusgraph", types.NamespacedName{Name: janusgraph.Name, Namespace: janusgraph.Namespace})
+	// look for resource of type PodList
+	podList := &corev1.PodList{}
+	//create filter to check for Pods only in our Namespace with the correct matching labels
+	listOpts := []client.ListOption{
+		client.InNamespace(janusgraph.Namespace),
+		client.MatchingLabels(labelsForJanusgraph(janusgraph.Name)),
+	}
+	//List all Pods that match our filter (same Namespace and matching labels)
+	if err := r.List(ctx, podList, listOpts...); err != nil {
+		log.Error(err, "Failed to list pods")
+		return err
+	}
+	//return an array of the names of pods whose Gremlin Server is ready
+	ready := readyPods(podList.Items)
+
+	//the last pod of a rolling update is replaced after the partition reached zero
+	if found.Status.UpdateRevision != "" && found.Status.CurrentRevision != found.Status.UpdateRevision {
+		upgrading = true
+	}
+
+	storageCondition, err := r.storageCondition(ctx, janusgraph)
+	if err != nil {
+		return err
+	}
+	serviceCondition, endpoint, err := r.serviceCondition(ctx, janusgraph)
+	if err != nil {
+		return err
+	}
+
+	//the Janusgraph read at the start of the reconcile may be stale by now, so the status is built on
+	//and patched against the latest copy read from the API server
+	latest := &graphv1beta1.Janusgraph{}
+	reader := r.APIReader
+	if reader == nil {
+		reader = r.Client
+	}
+	if err := reader.Get(ctx, types.NamespacedName{Name: janusgraph.Name, Namespace: janusgraph.Namespace}, latest); err != nil {
+		log.Error(err, "Failed to get Janusgraph")
+		return err
+	}
+	if latest.Generation != janusgraph.Generation {
+		//the spec changed meanwhile, the reconcile of the new spec records the status
+		return nil
+	}
+
+	//warn about insecure settings, e.g. a LoadBalancer Service without auth
+	warnings := warningsForJanusgraph(janusgraph)
+	if warning := storageWarning(janusgraph, found); warning != "" {
+		warnings = append(warnings, warning)
+	}
+	if !reflect.DeepEqual(warnings, latest.Status.Warnings) {
+		for _, warning := range warnings {
+			log.Info("Insecure Janusgraph spec", "Warning", warning)
+		}
+	}
+
+	status := graphv1beta1.JanusgraphStatus{
+		Nodes:              owned.PodNames(ready),
+		ReadyReplicas:      int32(len(ready)),
+		ObservedGeneration: janusgraph.Generation,
+		Version:            latest.Status.Version,
+		Endpoint:           endpoint,
+		Warnings:           warnings,
+		Conditions:         append([]metav1.Condition{}, latest.Status.Conditions...),
+	}
+	if !upgrading {
+		status.Version = janusgraph.Spec.Image.Version
+	}
+	//a scale down is over once the StatefulSet has been scaled down
+	if *found.Spec.Replicas > janusgraph.Spec.Replicas {
+		status.ScaleDown = latest.Status.ScaleDown
+	}
+
+	gremlinCondition := metav1.Condition{
+		Type:    conditionGremlinReady,
+		Status:  metav1.ConditionFalse,
+		Reason:  "PodsNotReady",
+		Message: fmt.Sprintf("%d of %d pods are ready", len(ready), janusgraph.Spec.Replicas),
+	}
+	if int32(len(ready)) >= janusgraph.Spec.Replicas && janusgraph.Spec.Replicas > 0 {
+		gremlinCondition.Status = metav1.ConditionTrue
+		gremlinCondition.Reason = "PodsReady"
+	}
+
+	upgradingCondition := metav1.Condition{
+		Type:    conditionUpgrading,
+		Status:  metav1.ConditionFalse,
+		Reason:  "UpToDate",
+		Message: "every pod runs version " + status.Version,
+	}
+	if upgrading {
+		upgradingCondition.Status = metav1.ConditionTrue
+		upgradingCondition.Reason = "RollingUpdate"
+		upgradingCondition.Message = "pods are being updated to version " + janusgraph.Spec.Image.Version
+	}
+
+	for _, condition := range []metav1.Condition{storageCondition, serviceCondition, gremlinCondition, upgradingCondition} {
+		condition.ObservedGeneration = janusgraph.Generation
+		meta.SetStatusCondition(&status.Conditions, condition)
+	}
+
+	if equality.Semantic.DeepEqual(status, latest.Status) {
+		janusgraph.Status = latest.Status
+		return nil
+	}
+	//only a change of version is recorded, the first version set when the Janusgraph is created is not an upgrade
+	upgraded := latest.Status.Version != "" && status.Version != latest.Status.Version
+	previous := latest.Status.Version
+	//the status is merge-patched rather than updated, so pods changing while the status was computed
+	//do not fail the write with a conflict; the next reconcile picks up their new state
+	patch := client.MergeFrom(latest.DeepCopy())
+	latest.Status = status
+	if err := r.Status().Patch(ctx, latest, patch); err != nil {
+		log.Error(err, "Failed to update Janusgraph status")
+		r.Recorder.Eventf(janusgraph, corev1.EventTypeWarning, owned.ReasonFailedUpdateStatus, "Failed to update status: %v", err)
+		return err
+	}
+	janusgraph.Status = latest.Status
+	if upgraded {
+		r.Recorder.Eventf(janusgraph, corev1.EventTypeNormal, reasonUpgraded, "Upgraded from version %s to %s", previous, status.Version)
+	}
+	return nil
+}
+
+//storageCondition returns the StorageReady condition: true when the data volume of every pod is bound,
+//or when the Janusgraph has no persistent storage
+func (r *JanusgraphReconciler) storageCondition(ctx context.Context, janusgraph *graphv1beta1.Janusgraph) (metav1.Condition, error) {
+	log := r.Log.WithValues("janusgraph", types.NamespacedName{Name: janusgraph.Name, Namespace: janusgraph.Namespace})
+	condition := metav1.Condition{
+		Type:    conditionStorageReady,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Ephemeral",
+		Message: "the pods have no persistent volumes, data is lost when a pod restarts",
+	}
+	if janusgraph.Spec.Storage == nil {
+		return condition, nil
+	}
+
+	claimList := &corev1.PersistentVolumeClaimList{}
+	listOpts := []client.ListOption{
+		client.InNamespace(janusgraph.Namespace),
+		client.MatchingLabels(labelsForJanusgraph(janusgraph.Name)),
+	}
+	if err := r.List(ctx, claimList, listOpts...); err != nil {
+		log.Error(err, "Failed to list PersistentVolumeClaims")
+		return condition, err
+	}
+	bound := map[string]bool{}
+	for _, claim := range claimList.Items {
+		bound[claim.Name] = claim.Status.Phase == corev1.ClaimBound
+	}
+	var pending []string
+	for i := int32(0); i < janusgraph.Spec.Replicas; i++ {
+		name := fmt.Sprintf("%s-%s-%d", dataVolumeName, janusgraph.Name, i)
+		if !bound[name] {
+			pending = append(pending, name)
+		}
+	}
+	if len(pending) > 0 {
+		sort.Strings(pending)
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "ClaimsPending"
+		condition.Message = "volumes are not bound: " + strings.Join(pending, ", ")
+		return condition, nil
+	}
+	condition.Reason = "ClaimsBound"
+	condition.Message = "the data volume of every pod is bound"
+	return condition, nil
+}
+
+//serviceCondition returns the ServiceReady condition and the Gremlin endpoint of the client Service.
+//The endpoint is the load balancer ingress of a LoadBalancer Service, otherwise the in-cluster address.
+func (r *JanusgraphReconciler) serviceCondition(ctx context.Context, janusgraph *graphv1beta1.Janusgraph) (metav1.Condition, string, error) {
+	log := r.Log.WithValues("janusgraph", types.NamespacedName{Name: janusgraph.Name, Namespace: janusgraph.Namespace})
+	condition := metav1.Condition{
+		Type:    conditionServiceReady,
+		Status:  metav1.ConditionFalse,
+		Reason:  "ServiceNotFound",
+		Message: "the client Service does not exist",
+	}
+	scheme := "ws"
+	if janusgraph.Spec.TLS != nil {
+		scheme = "wss"
+	}
+	port := gremlinServicePort(janusgraph)
+
+	srv := &corev1.Service{}
+	err := r.Get(ctx, types.NamespacedName{Name: janusgraph.Name + "-service", Namespace: janusgraph.Namespace}, srv)
+	if err != nil && errors.IsNotFound(err) {
+		return condition, "", nil
+	} else if err != nil {
+		log.Error(err, "Failed to get service")
+		return condition, "", err
+	}
+
+	if srv.Spec.Type == corev1.ServiceTypeLoadBalancer {
+		for _, ingress := range srv.Status.LoadBalancer.Ingress {
+			host := ingress.Hostname
+			if host == "" {
+				host = ingress.IP
+			}
+			if host == "" {
+				continue
+			}
+			condition.Status = metav1.ConditionTrue
+			condition.Reason = "LoadBalancerReady"
+			condition.Message = "the load balancer has an ingress"
+			return condition, fmt.Sprintf("%s://%s:%d/gremlin", scheme, host, port), nil
+		}
+		condition.Reason = "LoadBalancerPending"
+		condition.Message = "waiting for the load balancer ingress"
+		return condition, "", nil
+	}
+
+	condition.Status = metav1.ConditionTrue
+	condition.Reason = "ServiceReady"
+	condition.Message = "the client Service exists"
+	return condition, fmt.Sprintf("%s://%s:%d/gremlin", scheme, gremlinServiceHost(janusgraph), port), nil
+}
@@ -0,0 +1,464 @@
+/*
+Copyright 2021.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	graphv1alpha1 "github.com/example/janusgraph-operator/api/v1alpha1"
+	graphv1beta1 "github.com/example/janusgraph-operator/api/v1beta1"
+)
+
+const (
+	backupPhasePending   = "Pending"
+	backupPhaseRunning   = "Running"
+	backupPhaseScheduled = "Scheduled"
+	backupPhaseSucceeded = "Succeeded"
+	backupPhaseFailed    = "Failed"
+)
+
+// backupPath is where the volume holding backup files is mounted in backup and restore Jobs
+const backupPath = "/backup"
+
+// s3ClientImage is the image of the MinIO client used to copy backup files to and from S3 compatible stores
+const s3ClientImage = "minio/mc:latest"
+
+// maxCronJobNameLength is the longest name of a CronJob, since its Jobs are named after it with a suffix
+// of up to 11 characters and a Job name is limited to maxJobNameLength
+const maxCronJobNameLength = 52
+
+// jobTemplateHashAnnotation records on the CronJob of a scheduled backup a hash of the Job template it was
+// written with. The live template holds the defaults set by the API server, so it cannot be compared with
+// the desired one field by field.
+const jobTemplateHashAnnotation = "graph.example.com/job-template-hash"
+
+// backupRequeueDelay is how often scheduled backups are checked, since the Jobs of their CronJob are not watched
+const backupRequeueDelay = time.Minute
+
+// backupRunnerScript is the runner of backup Jobs. The Gremlin Server writes every vertex of the graph, with
+// its properties, meta-properties and edges, with the GraphSON writer of the graph, so the values keep their
+// types. The Job writes the vertices one per line to BACKUP_DIR, which is the file writeGraph would write,
+// named after the backup and the current time. The file is read back with the matching reader, so a backup
+// that could not be restored fails, and the file name and the number of vertices and edges are written to
+// the termination log as a JSON object.
+const backupRunnerScript = gremlinConnect + localGraph + `client = cluster.connect()
+options = org.apache.tinkerpop.gremlin.driver.RequestOptions.build().timeout(System.getenv('SCRIPT_TIMEOUT_MS') as long).create()
+
+exitCode = 0
+try {
+    dir = new File(System.getenv('BACKUP_DIR'))
+    dir.mkdirs()
+    file = new File(dir, System.getenv('BACKUP_NAME') + '-' + new Date().format('yyyyMMdd-HHmmss', TimeZone.getTimeZone('UTC')) + '.json')
+    file.withWriter('UTF-8') { out ->
+        client.submit('writer = graph.io(IoCore.graphson()).writer().create(); g.V().map { stream = new ByteArrayOutputStream(); writer.writeVertex(stream, it.get(), Direction.BOTH); stream.toString("UTF-8") }', options).each { result ->
+            out.write(result.getString())
+            out.write('\n')
+        }
+    }
+
+    graph = openGraph()
+    readGraphSON(graph, file.path)
+    new File('/dev/termination-log').text = groovy.json.JsonOutput.toJson([file: file.name, vertices: graph.vertices().size(), edges: graph.edges().size()])
+} catch (Exception e) {
+    new File('/dev/termination-log').text = e.getMessage() ?: e.toString()
+    exitCode = 1
+} finally {
+    client.close()
+    cluster.close()
+}
+System.exit(exitCode)
+`
+
+// JanusgraphBackupReconciler reconciles a JanusgraphBackup object
+type JanusgraphBackupReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=graph.example.com,resources=janusgraphbackups,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=graph.example.com,resources=janusgraphbackups/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=graph.example.com,resources=janusgraphbackups/finalizers,verbs=update
+// +kubebuilder:rbac:groups=graph.example.com,resources=janusgraphs,verbs=get;list;watch
+// +kubebuilder:rbac:groups=batch,resources=jobs;cronjobs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+
+// Reconcile backs up the Janusgraph referenced by a JanusgraphBackup object.
+// A backup without a schedule is taken once by a Job, a scheduled backup by the Jobs of a CronJob.
+// The most recent successful backup is recorded in the status of the JanusgraphBackup.
+func (r *JanusgraphBackupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("janusgraphbackup", req.NamespacedName)
+
+	// Fetch the JanusgraphBackup instance
+	backup := &graphv1alpha1.JanusgraphBackup{}
+	err := r.Get(ctx, req.NamespacedName, backup)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// Request object not found, could have been deleted after reconcile request.
+			// Owned objects are automatically garbage collected.
+			log.Info("JanusgraphBackup resource not found. Ignoring since object must be deleted")
+			return ctrl.Result{}, nil
+		}
+		// Error reading the object - requeue the request.
+		log.Error(err, "Failed to get JanusgraphBackup")
+		return ctrl.Result{}, err
+	}
+
+	// a backup without a schedule is only taken once
+	if backup.Spec.Schedule == "" && (backup.Status.Phase == backupPhaseSucceeded || backup.Status.Phase == backupPhaseFailed) {
+		return ctrl.Result{}, nil
+	}
+
+	janusgraph := &graphv1beta1.Janusgraph{}
+	err = r.Get(ctx, types.NamespacedName{Name: backup.Spec.JanusgraphRef, Namespace: backup.Namespace}, janusgraph)
+	if err != nil && errors.IsNotFound(err) {
+		return r.setPhase(ctx, backup, backupPhasePending, "Janusgraph "+backup.Spec.JanusgraphRef+" not found")
+	} else if err != nil {
+		log.Error(err, "Failed to get Janusgraph")
+		return ctrl.Result{}, err
+	}
+
+	// the name is shared by the ConfigMap, the Job of a backup and the CronJob of a scheduled backup
+	name := boundedName(backup.Name+"-backup", maxCronJobNameLength)
+	configMap := gremlinScriptConfigMap(name, janusgraph, map[string]string{"runner.groovy": backupRunnerScript})
+	ctrl.SetControllerReference(backup, configMap, r.Scheme)
+	err = r.Get(ctx, types.NamespacedName{Name: name, Namespace: backup.Namespace}, &corev1.ConfigMap{})
+	if err != nil && errors.IsNotFound(err) {
+		log.Info("Creating a new ConfigMap", "ConfigMap.Namespace", configMap.Namespace, "ConfigMap.Name", configMap.Name)
+		if err = r.Create(ctx, configMap); err != nil {
+			log.Error(err, "Failed to create new ConfigMap", "ConfigMap.Namespace", configMap.Namespace, "ConfigMap.Name", configMap.Name)
+			return ctrl.Result{}, err
+		}
+	} else if err != nil {
+		log.Error(err, "Failed to get ConfigMap")
+		return ctrl.Result{}, err
+	}
+
+	job, err := r.jobForBackup(backup, janusgraph, name)
+	if err != nil {
+		return r.setPhase(ctx, backup, backupPhaseFailed, err.Error())
+	}
+	if backup.Spec.Schedule != "" {
+		return r.ensureCronJob(ctx, backup, job)
+	}
+
+	found := &batchv1.Job{}
+	err = r.Get(ctx, types.NamespacedName{Name: name, Namespace: backup.Namespace}, found)
+	if err != nil && errors.IsNotFound(err) {
+		// the graph can only be exported once the Gremlin Server is serving queries
+		if len(janusgraph.Status.Nodes) == 0 {
+			return r.setPhase(ctx, backup, backupPhasePending, "Waiting for Janusgraph "+janusgraph.Name+" to become ready")
+		}
+		ctrl.SetControllerReference(backup, job, r.Scheme)
+		log.Info("Creating a new Job", "Job.Namespace", job.Namespace, "Job.Name", job.Name)
+		if err = r.Create(ctx, job); err != nil {
+			log.Error(err, "Failed to create new Job", "Job.Namespace", job.Namespace, "Job.Name", job.Name)
+			return ctrl.Result{}, err
+		}
+		return r.setPhase(ctx, backup, backupPhaseRunning, "")
+	} else if err != nil {
+		log.Error(err, "Failed to get Job")
+		return ctrl.Result{}, err
+	}
+
+	finished, failed := jobFinished(found)
+	if !finished {
+		// the Job is owned by the backup, so its completion triggers the next reconcile
+		return ctrl.Result{}, nil
+	}
+	if err := r.recordBackup(ctx, backup, found, failed); err != nil {
+		log.Error(err, "Failed to read Job output", "Job.Namespace", found.Namespace, "Job.Name", found.Name)
+		return ctrl.Result{}, err
+	}
+	if failed {
+		return r.setPhase(ctx, backup, backupPhaseFailed, backup.Status.Message)
+	}
+	return r.setPhase(ctx, backup, backupPhaseSucceeded, "")
+}
+
+// ensureCronJob creates the CronJob of a scheduled backup, keeps its schedule and Job template up to date
+// and records the result of the most recently finished backup Job
+func (r *JanusgraphBackupReconciler) ensureCronJob(ctx context.Context, backup *graphv1alpha1.JanusgraphBackup,
+	job *batchv1.Job) (ctrl.Result, error) {
+	log := r.Log.WithValues("janusgraphbackup", types.NamespacedName{Name: backup.Name, Namespace: backup.Namespace})
+
+	cronJob := &batchv1beta1.CronJob{
+		ObjectMeta: job.ObjectMeta,
+		Spec: batchv1beta1.CronJobSpec{
+			Schedule:          backup.Spec.Schedule,
+			ConcurrencyPolicy: batchv1beta1.ForbidConcurrent,
+			JobTemplate: batchv1beta1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: job.Labels},
+				Spec:       job.Spec,
+			},
+		},
+	}
+	templateHash, err := jobTemplateHash(&cronJob.Spec.JobTemplate)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	cronJob.Annotations = map[string]string{jobTemplateHashAnnotation: templateHash}
+	ctrl.SetControllerReference(backup, cronJob, r.Scheme)
+
+	found := &batchv1beta1.CronJob{}
+	err = r.Get(ctx, types.NamespacedName{Name: cronJob.Name, Namespace: cronJob.Namespace}, found)
+	if err != nil && errors.IsNotFound(err) {
+		log.Info("Creating a new CronJob", "CronJob.Namespace", cronJob.Namespace, "CronJob.Name", cronJob.Name)
+		if err = r.Create(ctx, cronJob); err != nil {
+			log.Error(err, "Failed to create new CronJob", "CronJob.Namespace", cronJob.Namespace, "CronJob.Name", cronJob.Name)
+			return ctrl.Result{}, err
+		}
+		return r.setPhase(ctx, backup, backupPhaseScheduled, "")
+	} else if err != nil {
+		log.Error(err, "Failed to get CronJob")
+		return ctrl.Result{}, err
+	}
+	// the whole Job template is replaced when any of its fields changed, e.g. the image, the storage or the auth
+	if found.Spec.Schedule != cronJob.Spec.Schedule || found.Annotations[jobTemplateHashAnnotation] != templateHash {
+		log.Info("Updating CronJob", "CronJob.Namespace", found.Namespace, "CronJob.Name", found.Name)
+		updated := found.DeepCopy()
+		updated.Spec.Schedule = cronJob.Spec.Schedule
+		updated.Spec.JobTemplate = cronJob.Spec.JobTemplate
+		if updated.Annotations == nil {
+			updated.Annotations = map[string]string{}
+		}
+		updated.Annotations[jobTemplateHashAnnotation] = templateHash
+		if err = r.Patch(ctx, updated, client.MergeFrom(found)); err != nil {
+			log.Error(err, "Failed to update CronJob", "CronJob.Namespace", found.Namespace, "CronJob.Name", found.Name)
+			return ctrl.Result{}, err
+		}
+	}
+
+	// record the most recently finished backup Job of the CronJob
+	jobList := &batchv1.JobList{}
+	listOpts := []client.ListOption{
+		client.InNamespace(backup.Namespace),
+		client.MatchingLabels(labelsForBackup(backup)),
+	}
+	if err = r.List(ctx, jobList, listOpts...); err != nil {
+		log.Error(err, "Failed to list Jobs", "JanusgraphBackup.Namespace", backup.Namespace, "JanusgraphBackup.Name", backup.Name)
+		return ctrl.Result{}, err
+	}
+	var latest *batchv1.Job
+	for i := range jobList.Items {
+		job := &jobList.Items[i]
+		if finished, _ := jobFinished(job); finished && (latest == nil || latest.CreationTimestamp.Before(&job.CreationTimestamp)) {
+			latest = job
+		}
+	}
+	if latest != nil {
+		_, failed := jobFinished(latest)
+		if err = r.recordBackup(ctx, backup, latest, failed); err != nil {
+			log.Error(err, "Failed to read Job output", "Job.Namespace", latest.Namespace, "Job.Name", latest.Name)
+			return ctrl.Result{}, err
+		}
+	}
+	if _, err = r.setPhase(ctx, backup, backupPhaseScheduled, backup.Status.Message); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: backupRequeueDelay}, nil
+}
+
+// jobTemplateHash returns a hash of the JSON encoding of a Job template
+func jobTemplateHash(template *batchv1beta1.JobTemplateSpec) (string, error) {
+	data, err := json.Marshal(template)
+	if err != nil {
+		return "", err
+	}
+	hash := fnv.New32a()
+	hash.Write(data)
+	return fmt.Sprintf("%08x", hash.Sum32()), nil
+}
+
+// recordBackup stores the result of a finished backup Job in the status of the backup.
+// A failed Job sets the message of the status, a successful one replaces the last backup.
+func (r *JanusgraphBackupReconciler) recordBackup(ctx context.Context, backup *graphv1alpha1.JanusgraphBackup,
+	job *batchv1.Job, failed bool) error {
+	output, err := gremlinJobOutput(ctx, r.Client, job)
+	if err != nil {
+		return err
+	}
+	if failed {
+		backup.Status.Message = "Backup " + job.Name + " failed: " + output
+		return nil
+	}
+
+	// the backup runner returns the file name and the number of vertices and edges as a JSON object
+	result := struct {
+		File     string `json:"file"`
+		Vertices int64  `json:"vertices"`
+		Edges    int64  `json:"edges"`
+	}{}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		backup.Status.Message = "Unexpected output of backup " + job.Name + ": " + output
+		return nil
+	}
+	finishedAt := job.CreationTimestamp
+	if job.Status.CompletionTime != nil {
+		finishedAt = *job.Status.CompletionTime
+	}
+	backup.Status.Message = ""
+	backup.Status.LastBackup = &graphv1alpha1.BackupRecord{
+		File:     result.File,
+		Location: backupLocation(&backup.Spec.Storage, result.File),
+		Time:     finishedAt,
+		Vertices: result.Vertices,
+		Edges:    result.Edges,
+	}
+	return nil
+}
+
+// jobForBackup returns the Job exporting the graph of a Janusgraph into the storage of the backup.
+// Backups to S3 are first written to an emptyDir by the Gremlin Console running as an init container,
+// then uploaded by the MinIO client.
+func (r *JanusgraphBackupReconciler) jobForBackup(backup *graphv1alpha1.JanusgraphBackup, jg *graphv1beta1.Janusgraph,
+	name string) (*batchv1.Job, error) {
+	volume, dir, err := backupVolume(&backup.Spec.Storage)
+	if err != nil {
+		return nil, err
+	}
+	timeout := time.Duration(backup.Spec.TimeoutSeconds) * time.Second
+	if timeout == 0 {
+		timeout = 30 * time.Minute
+	}
+
+	job := gremlinScriptJob(name, jg, name, timeout)
+	job.Labels = labelsForBackup(backup)
+	podSpec := &job.Spec.Template.Spec
+	podSpec.Volumes = append(podSpec.Volumes, volume)
+	gremlin := &podSpec.Containers[0]
+	gremlin.VolumeMounts = append(gremlin.VolumeMounts, corev1.VolumeMount{Name: volume.Name, MountPath: backupPath})
+	gremlin.Env = append(gremlin.Env,
+		corev1.EnvVar{Name: "BACKUP_DIR", Value: dir},
+		corev1.EnvVar{Name: "BACKUP_NAME", Value: backup.Name},
+	)
+
+	if s3 := backup.Spec.Storage.S3; s3 != nil {
+		upload := s3ClientContainer("upload", s3, volume.Name,
+			`mc mb --ignore-existing "backup/$S3_BUCKET"; for f in `+backupPath+`/*.json; do mc cp "$f" "backup/$S3_BUCKET/${S3_PREFIX}$(basename "$f")"; done`)
+		podSpec.InitContainers = []corev1.Container{*gremlin}
+		podSpec.Containers = []corev1.Container{upload}
+	}
+	return job, nil
+}
+
+// labelsForBackup returns the labels of the Jobs taking backups for the given backup.
+// They are only set on the Jobs, the pods must not match the selector of the Janusgraph Services.
+func labelsForBackup(backup *graphv1alpha1.JanusgraphBackup) map[string]string {
+	return map[string]string{"app": "Janusgraph-backup", "janusgraphbackup_cr": boundedName(backup.Name, validation.LabelValueMaxLength)}
+}
+
+// setPhase records the phase and message of the backup.
+// Pending backups are requeued since the Janusgraph they wait for is not watched.
+func (r *JanusgraphBackupReconciler) setPhase(ctx context.Context, backup *graphv1alpha1.JanusgraphBackup,
+	phase string, message string) (ctrl.Result, error) {
+	backup.Status.Phase = phase
+	backup.Status.Message = message
+	if err := r.Status().Update(ctx, backup); err != nil {
+		r.Log.Error(err, "Failed to update JanusgraphBackup status", "JanusgraphBackup.Namespace", backup.Namespace, "JanusgraphBackup.Name", backup.Name)
+		return ctrl.Result{}, err
+	}
+	if phase == backupPhasePending {
+		return ctrl.Result{RequeueAfter: backupRequeueDelay}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *JanusgraphBackupReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&graphv1alpha1.JanusgraphBackup{}).
+		Owns(&batchv1.Job{}).
+		Owns(&batchv1beta1.CronJob{}).
+		Owns(&corev1.ConfigMap{}).
+		Complete(r)
+}
+
+// backupVolume returns the volume mounted under /backup in backup and restore Jobs, and the directory
+// of the backup files once mounted. S3 storage uses an emptyDir shared with the MinIO client.
+func backupVolume(storage *graphv1alpha1.BackupStorage) (corev1.Volume, string, error) {
+	volume := corev1.Volume{Name: "backup"}
+	switch {
+	case storage.PersistentVolumeClaim != nil && storage.S3 != nil:
+		return volume, "", fmt.Errorf("only one of storage.persistentVolumeClaim and storage.s3 may be set")
+	case storage.PersistentVolumeClaim != nil:
+		volume.PersistentVolumeClaim = &corev1.PersistentVolumeClaimVolumeSource{
+			ClaimName: storage.PersistentVolumeClaim.ClaimName,
+		}
+		return volume, path.Join(backupPath, storage.PersistentVolumeClaim.Path), nil
+	case storage.S3 != nil:
+		volume.EmptyDir = &corev1.EmptyDirVolumeSource{}
+		return volume, backupPath, nil
+	}
+	return volume, "", fmt.Errorf("one of storage.persistentVolumeClaim and storage.s3 must be set")
+}
+
+// backupLocation returns the full location of a backup file in the given storage
+func backupLocation(storage *graphv1alpha1.BackupStorage, file string) string {
+	if storage.S3 != nil {
+		return "s3://" + storage.S3.Bucket + "/" + storage.S3.Prefix + file
+	}
+	return "pvc://" + path.Join(storage.PersistentVolumeClaim.ClaimName, storage.PersistentVolumeClaim.Path, file)
+}
+
+// s3ClientContainer returns a MinIO client container that runs command with the alias "backup"
+// pointing at the object store, and the backup volume mounted under /backup
+func s3ClientContainer(name string, s3 *graphv1alpha1.S3Storage, volumeName string, command string) corev1.Container {
+	secretKey := func(key string) *corev1.EnvVarSource {
+		return &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: s3.CredentialsSecret},
+				Key:                  key,
+			},
+		}
+	}
+	return corev1.Container{
+		Name:    name,
+		Image:   s3ClientImage,
+		Command: []string{"/bin/sh", "-c"},
+		Args: []string{
+			`set -e; mc alias set backup "$S3_ENDPOINT" "$AWS_ACCESS_KEY_ID" "$AWS_SECRET_ACCESS_KEY"; ` + command,
+		},
+		Env: []corev1.EnvVar{
+			{Name: "S3_ENDPOINT", Value: s3.Endpoint},
+			{Name: "S3_BUCKET", Value: s3.Bucket},
+			{Name: "S3_PREFIX", Value: s3.Prefix},
+			{Name: "AWS_ACCESS_KEY_ID", ValueFrom: secretKey("accessKeyID")},
+			{Name: "AWS_SECRET_ACCESS_KEY", ValueFrom: secretKey("secretAccessKey")},
+			{Name: "MC_INSECURE", Value: strconv.FormatBool(s3.Insecure)},
+			// mc keeps its configuration in the home directory, which is not writable in restricted pods
+			{Name: "MC_CONFIG_DIR", Value: "/tmp/.mc"},
+		},
+		VolumeMounts: []corev1.VolumeMount{{Name: volumeName, MountPath: backupPath}},
+	}
+}
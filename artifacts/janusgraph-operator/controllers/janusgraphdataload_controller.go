@@ -0,0 +1,393 @@
+/*
+Copyright 2021.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	graphv1alpha1 "github.com/example/janusgraph-operator/api/v1alpha1"
+	graphv1beta1 "github.com/example/janusgraph-operator/api/v1beta1"
+)
+
+const (
+	dataLoadPhasePending   = "Pending"
+	dataLoadPhaseRunning   = "Running"
+	dataLoadPhaseSucceeded = "Succeeded"
+	dataLoadPhaseFailed    = "Failed"
+)
+
+// dataLoadPath is where the volume holding the data file is mounted in the loader Job
+const dataLoadPath = "/data"
+
+// dataLoadRunnerScript is the runner of data load Jobs. Groovy scripts are submitted to the Gremlin Server
+// as they are and the vertices and edges they add are counted. GraphSON, GraphML and CSV files are parsed
+// into a local TinkerGraph, whose vertices and then edges are written to the graph in batches. GraphSON files
+// are read with the reader matching the writer of the backups, and every value of a multi-valued property
+// and the meta-properties are written back. CSV files
+// are read as RFC 4180 describes them, so quoted fields may hold commas, line breaks and doubled quotes.
+// The number of vertices and edges added is written to the termination log as a JSON object.
+// When EXISTING_GRAPH is set a graph that already has vertices is either dropped first (Drop) or
+// left untouched and the load fails (Reject), otherwise the file is added to the existing graph.
+const dataLoadRunnerScript = gremlinConnect + localGraph + `client = cluster.connect()
+timeout = System.getenv('SCRIPT_TIMEOUT_MS') as long
+batchSize = System.getenv('BATCH_SIZE') as int
+file = new File(System.getenv('DATA_FILE'))
+format = System.getenv('DATA_FORMAT')
+
+submit = { String script, Map params ->
+    builder = org.apache.tinkerpop.gremlin.driver.RequestOptions.build().timeout(timeout)
+    params.each { key, value -> builder.addParameter(key, value) }
+    client.submit(script, builder.create()).all().get()
+}
+count = {
+    submit('g.V().count().next() + "," + g.E().count().next()', [:])[0].getString().tokenize(',')*.toLong()
+}
+// parseCsv splits the text of a CSV file into records of fields. A quoted field may hold commas,
+// line breaks and quotes written twice. Blank lines are skipped.
+parseCsv = { String text ->
+    def records = []
+    def record = []
+    def field = new StringBuilder()
+    def quoted = false
+    def i = 0
+    while (i < text.length()) {
+        def c = text[i]
+        if (quoted) {
+            if (c == '"' && i + 1 < text.length() && text[i + 1] == '"') {
+                field.append(c)
+                i++
+            } else if (c == '"') {
+                quoted = false
+            } else {
+                field.append(c)
+            }
+        } else if (c == '"') {
+            quoted = true
+        } else if (c == ',') {
+            record << field.toString()
+            field.setLength(0)
+        } else if (c == '\n') {
+            record << field.toString()
+            field.setLength(0)
+            records << record
+            record = []
+        } else if (c != '\r') {
+            field.append(c)
+        }
+        i++
+    }
+    if (quoted) {
+        throw new IllegalArgumentException('the CSV file ends inside a quoted field')
+    }
+    if (field.length() > 0 || record) {
+        record << field.toString()
+        records << record
+    }
+    records.findAll { r -> r.size() > 1 || r[0].trim() }
+}
+
+exitCode = 0
+try {
+    existing = System.getenv('EXISTING_GRAPH')
+    if (existing && count()[0] > 0) {
+        if (existing != 'Drop') {
+            throw new IllegalStateException('the graph is not empty, set existingGraph to Drop to replace its data')
+        }
+        submit('g.V().drop().iterate()', [:])
+    }
+    counts = [vertices: 0L, edges: 0L]
+    if (format == 'Groovy') {
+        before = count()
+        submit(file.text, [:])
+        after = count()
+        counts = [vertices: after[0] - before[0], edges: after[1] - before[1]]
+    } else {
+        source = openGraph()
+        if (format == 'GraphSON') {
+            readGraphSON(source, file.path)
+        } else if (format == 'GraphML') {
+            source.io(IoCore.graphml()).readGraph(file.path)
+        } else {
+            records = parseCsv(file.text)
+            if (!records) {
+                throw new IllegalArgumentException('the CSV file has no header')
+            }
+            header = records[0]*.trim()
+            records.drop(1).eachWithIndex { record, row ->
+                if (record.size() > header.size()) {
+                    throw new IllegalArgumentException("record ${row + 2} of the CSV file has ${record.size()} fields, the header has ${header.size()}")
+                }
+                vertex = source.addVertex(T.label, System.getenv('CSV_VERTEX_LABEL'))
+                record.eachWithIndex { value, i ->
+                    if (value.trim()) vertex.property(header[i], value.trim())
+                }
+            }
+        }
+
+        ids = [:]
+        source.vertices().toList().collate(batchSize).each { vertices ->
+            batch = vertices.collect { v -> [label: v.label(), properties: v.properties().collect { p -> [key: p.key(), value: p.value(), meta: p.properties().collectMany { m -> [m.key(), m.value()] }] }] }
+            created = submit('batch.collect { e -> v = graph.addVertex(T.label, e.label); e.properties.each { p -> v.property(p.key, p.value, *p.meta) }; v.id() }', [batch: batch])
+            vertices.eachWithIndex { v, i -> ids[v.id()] = created[i].getString().toLong() }
+            counts.vertices += vertices.size()
+        }
+        source.edges().toList().collate(batchSize).each { edges ->
+            batch = edges.collect { e -> [label: e.label(), outId: ids[e.outVertex().id()], inId: ids[e.inVertex().id()], properties: e.properties().collectEntries { p -> [(p.key()): p.value()] }] }
+            submit('batch.each { e -> edge = g.V(e.outId).next().addEdge(e.label, g.V(e.inId).next()); e.properties.each { k, val -> edge.property(k, val) } }; batch.size()', [batch: batch])
+            counts.edges += edges.size()
+        }
+    }
+    new File('/dev/termination-log').text = groovy.json.JsonOutput.toJson(counts)
+} catch (Exception e) {
+    new File('/dev/termination-log').text = e.getMessage() ?: e.toString()
+    exitCode = 1
+} finally {
+    client.close()
+    cluster.close()
+}
+System.exit(exitCode)
+`
+
+// dataLoadRequeueDelay is how long to wait before checking on a Janusgraph that is not ready yet
+const dataLoadRequeueDelay = 30 * time.Second
+
+// JanusgraphDataLoadReconciler reconciles a JanusgraphDataLoad object
+type JanusgraphDataLoadReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=graph.example.com,resources=janusgraphdataloads,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=graph.example.com,resources=janusgraphdataloads/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=graph.example.com,resources=janusgraphdataloads/finalizers,verbs=update
+// +kubebuilder:rbac:groups=graph.example.com,resources=janusgraphs,verbs=get;list;watch
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+
+// Reconcile loads the data of a JanusgraphDataLoad object into the referenced Janusgraph.
+// Once the Janusgraph has ready pods a Job runs the loader against its Gremlin Server, and when
+// the Job finishes the number of vertices and edges added, or the error, is recorded in the status.
+func (r *JanusgraphDataLoadReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("janusgraphdataload", req.NamespacedName)
+
+	// Fetch the JanusgraphDataLoad instance
+	dataLoad := &graphv1alpha1.JanusgraphDataLoad{}
+	err := r.Get(ctx, req.NamespacedName, dataLoad)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// Request object not found, could have been deleted after reconcile request.
+			// Owned objects are automatically garbage collected.
+			log.Info("JanusgraphDataLoad resource not found. Ignoring since object must be deleted")
+			return ctrl.Result{}, nil
+		}
+		// Error reading the object - requeue the request.
+		log.Error(err, "Failed to get JanusgraphDataLoad")
+		return ctrl.Result{}, err
+	}
+
+	// a data load runs only once
+	if dataLoad.Status.Phase == dataLoadPhaseSucceeded || dataLoad.Status.Phase == dataLoadPhaseFailed {
+		return ctrl.Result{}, nil
+	}
+
+	name := boundedName(dataLoad.Name+"-load", maxJobNameLength)
+	job := &batchv1.Job{}
+	err = r.Get(ctx, types.NamespacedName{Name: name, Namespace: dataLoad.Namespace}, job)
+	if err != nil && errors.IsNotFound(err) {
+		return r.startDataLoad(ctx, dataLoad, name)
+	} else if err != nil {
+		log.Error(err, "Failed to get Job")
+		return ctrl.Result{}, err
+	}
+
+	finished, failed := jobFinished(job)
+	if !finished {
+		// the Job is owned by the data load, so its completion triggers the next reconcile
+		return ctrl.Result{}, nil
+	}
+	output, err := gremlinJobOutput(ctx, r.Client, job)
+	if err != nil {
+		log.Error(err, "Failed to read Job output", "Job.Namespace", job.Namespace, "Job.Name", job.Name)
+		return ctrl.Result{}, err
+	}
+	now := metav1.Now()
+	dataLoad.Status.CompletionTime = &now
+	if failed {
+		if output == "" {
+			// the pod was stopped before the loader could report, e.g. it was evicted or ran out of memory
+			output = "The loader Job " + job.Name + " failed without an error, see the events of its pod"
+		}
+		return r.setPhase(ctx, dataLoad, dataLoadPhaseFailed, output)
+	}
+
+	// the loader returns the number of vertices and edges it added as a JSON object
+	counts := struct {
+		Vertices int64 `json:"vertices"`
+		Edges    int64 `json:"edges"`
+	}{}
+	if err := json.Unmarshal([]byte(output), &counts); err != nil {
+		return r.setPhase(ctx, dataLoad, dataLoadPhaseFailed, "Unexpected output of the loader: "+output)
+	}
+	dataLoad.Status.Vertices = counts.Vertices
+	dataLoad.Status.Edges = counts.Edges
+	return r.setPhase(ctx, dataLoad, dataLoadPhaseSucceeded, "")
+}
+
+// startDataLoad creates the ConfigMap and Job of the loader once the referenced Janusgraph is ready
+func (r *JanusgraphDataLoadReconciler) startDataLoad(ctx context.Context, dataLoad *graphv1alpha1.JanusgraphDataLoad,
+	name string) (ctrl.Result, error) {
+	log := r.Log.WithValues("janusgraphdataload", types.NamespacedName{Name: dataLoad.Name, Namespace: dataLoad.Namespace})
+
+	// the data can only be loaded once the Gremlin Server of the Janusgraph is serving queries
+	janusgraph := &graphv1beta1.Janusgraph{}
+	err := r.Get(ctx, types.NamespacedName{Name: dataLoad.Spec.JanusgraphRef, Namespace: dataLoad.Namespace}, janusgraph)
+	if err != nil && errors.IsNotFound(err) {
+		return r.setPhase(ctx, dataLoad, dataLoadPhasePending, "Janusgraph "+dataLoad.Spec.JanusgraphRef+" not found")
+	} else if err != nil {
+		log.Error(err, "Failed to get Janusgraph")
+		return ctrl.Result{}, err
+	}
+	if len(janusgraph.Status.Nodes) == 0 {
+		return r.setPhase(ctx, dataLoad, dataLoadPhasePending, "Waiting for Janusgraph "+janusgraph.Name+" to become ready")
+	}
+
+	volume, dataFile, err := dataLoadVolume(&dataLoad.Spec.Source)
+	if err != nil {
+		return r.setPhase(ctx, dataLoad, dataLoadPhaseFailed, err.Error())
+	}
+
+	configMap := gremlinScriptConfigMap(name, janusgraph, map[string]string{"runner.groovy": dataLoadRunnerScript})
+	ctrl.SetControllerReference(dataLoad, configMap, r.Scheme)
+	err = r.Get(ctx, types.NamespacedName{Name: name, Namespace: dataLoad.Namespace}, &corev1.ConfigMap{})
+	if err != nil && errors.IsNotFound(err) {
+		log.Info("Creating a new ConfigMap", "ConfigMap.Namespace", configMap.Namespace, "ConfigMap.Name", configMap.Name)
+		if err = r.Create(ctx, configMap); err != nil {
+			log.Error(err, "Failed to create new ConfigMap", "ConfigMap.Namespace", configMap.Namespace, "ConfigMap.Name", configMap.Name)
+			return ctrl.Result{}, err
+		}
+	} else if err != nil {
+		log.Error(err, "Failed to get ConfigMap")
+		return ctrl.Result{}, err
+	}
+
+	job := r.jobForDataLoad(dataLoad, janusgraph, name, volume, dataFile)
+	log.Info("Creating a new Job", "Job.Namespace", job.Namespace, "Job.Name", job.Name)
+	if err = r.Create(ctx, job); err != nil {
+		log.Error(err, "Failed to create new Job", "Job.Namespace", job.Namespace, "Job.Name", job.Name)
+		return ctrl.Result{}, err
+	}
+	return r.setPhase(ctx, dataLoad, dataLoadPhaseRunning, "")
+}
+
+// jobForDataLoad returns the loader Job of a data load, with the data file mounted under /data
+func (r *JanusgraphDataLoadReconciler) jobForDataLoad(dataLoad *graphv1alpha1.JanusgraphDataLoad, jg *graphv1beta1.Janusgraph,
+	name string, volume corev1.Volume, dataFile string) *batchv1.Job {
+	timeout := time.Duration(dataLoad.Spec.TimeoutSeconds) * time.Second
+	if timeout == 0 {
+		timeout = 10 * time.Minute
+	}
+	batchSize := dataLoad.Spec.BatchSize
+	if batchSize == 0 {
+		batchSize = 500
+	}
+	vertexLabel := dataLoad.Spec.CSVVertexLabel
+	if vertexLabel == "" {
+		vertexLabel = "vertex"
+	}
+
+	job := gremlinScriptJob(name, jg, name, timeout)
+	// a retried loader would add the vertices of the batches written before the failure a second time,
+	// so the Job is not retried and its first failure is recorded in the status
+	backoffLimit := int32(0)
+	job.Spec.BackoffLimit = &backoffLimit
+	podSpec := &job.Spec.Template.Spec
+	podSpec.Volumes = append(podSpec.Volumes, volume)
+	container := &podSpec.Containers[0]
+	container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+		Name:      volume.Name,
+		MountPath: dataLoadPath,
+		ReadOnly:  true,
+	})
+	container.Env = append(container.Env,
+		corev1.EnvVar{Name: "DATA_FILE", Value: dataFile},
+		corev1.EnvVar{Name: "DATA_FORMAT", Value: dataLoad.Spec.Format},
+		corev1.EnvVar{Name: "BATCH_SIZE", Value: fmt.Sprint(batchSize)},
+		corev1.EnvVar{Name: "CSV_VERTEX_LABEL", Value: vertexLabel},
+	)
+	ctrl.SetControllerReference(dataLoad, job, r.Scheme)
+	return job
+}
+
+// dataLoadVolume returns the volume holding the data file and the path of the file once mounted
+func dataLoadVolume(source *graphv1alpha1.DataLoadSource) (corev1.Volume, string, error) {
+	volume := corev1.Volume{Name: "data"}
+	switch {
+	case source.ConfigMap != nil && source.PersistentVolumeClaim != nil:
+		return volume, "", fmt.Errorf("only one of source.configMap and source.persistentVolumeClaim may be set")
+	case source.ConfigMap != nil:
+		volume.ConfigMap = &corev1.ConfigMapVolumeSource{
+			LocalObjectReference: source.ConfigMap.LocalObjectReference,
+			Items:                []corev1.KeyToPath{{Key: source.ConfigMap.Key, Path: source.ConfigMap.Key}},
+		}
+		return volume, path.Join(dataLoadPath, source.ConfigMap.Key), nil
+	case source.PersistentVolumeClaim != nil:
+		volume.PersistentVolumeClaim = &corev1.PersistentVolumeClaimVolumeSource{
+			ClaimName: source.PersistentVolumeClaim.ClaimName,
+			ReadOnly:  true,
+		}
+		return volume, path.Join(dataLoadPath, source.PersistentVolumeClaim.Path), nil
+	}
+	return volume, "", fmt.Errorf("one of source.configMap and source.persistentVolumeClaim must be set")
+}
+
+// setPhase records the phase and message of the data load.
+// Pending data loads are requeued since the Janusgraph they wait for is not watched.
+func (r *JanusgraphDataLoadReconciler) setPhase(ctx context.Context, dataLoad *graphv1alpha1.JanusgraphDataLoad,
+	phase string, message string) (ctrl.Result, error) {
+	dataLoad.Status.Phase = phase
+	dataLoad.Status.Message = message
+	if err := r.Status().Update(ctx, dataLoad); err != nil {
+		r.Log.Error(err, "Failed to update JanusgraphDataLoad status", "JanusgraphDataLoad.Namespace", dataLoad.Namespace, "JanusgraphDataLoad.Name", dataLoad.Name)
+		return ctrl.Result{}, err
+	}
+	if phase == dataLoadPhasePending {
+		return ctrl.Result{RequeueAfter: dataLoadRequeueDelay}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *JanusgraphDataLoadReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&graphv1alpha1.JanusgraphDataLoad{}).
+		Owns(&batchv1.Job{}).
+		Owns(&corev1.ConfigMap{}).
+		Complete(r)
+}
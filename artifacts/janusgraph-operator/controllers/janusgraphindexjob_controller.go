@@ -0,0 +1,324 @@
+/*
+Copyright 2021.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	graphv1alpha1 "github.com/example/janusgraph-operator/api/v1alpha1"
+	graphv1beta1 "github.com/example/janusgraph-operator/api/v1beta1"
+)
+
+const (
+	indexJobPhasePending   = "Pending"
+	indexJobPhaseRunning   = "Running"
+	indexJobPhaseSucceeded = "Succeeded"
+	indexJobPhaseFailed    = "Failed"
+)
+
+// indexJobRequeueDelay is how long to wait before checking on a Janusgraph that is not ready yet
+const indexJobRequeueDelay = 30 * time.Second
+
+// indexJobSteps are the management actions run, in order, for every action of a JanusgraphIndexJob
+var indexJobSteps = map[string][]string{
+	"REINDEX": {"REGISTER_INDEX", "REINDEX"},
+	"ENABLE":  {"REGISTER_INDEX", "ENABLE_INDEX"},
+	"DISABLE": {"DISABLE_INDEX"},
+	"REMOVE":  {"DISABLE_INDEX", "REMOVE_INDEX"},
+}
+
+// indexStepScripts run a single management action on the index named by indexName and wait for the
+// index to reach the resulting status. Steps are skipped when the index already has that status,
+// so a step that is run again after a failure picks up where it left off.
+var indexStepScripts = map[string]string{
+	"REGISTER_INDEX": `if (statusOf(index) == schemaStatus.INSTALLED) {
+    mgmt.updateIndex(index, schemaAction.REGISTER_INDEX).get()
+    mgmt.commit()
+    awaitStatus(schemaStatus.REGISTERED)
+} else {
+    mgmt.rollback()
+}
+`,
+	"ENABLE_INDEX": `current = statusOf(index)
+if (current == schemaStatus.REGISTERED) {
+    mgmt.updateIndex(index, schemaAction.ENABLE_INDEX).get()
+    mgmt.commit()
+    awaitStatus(schemaStatus.ENABLED)
+} else {
+    mgmt.rollback()
+    if (current != schemaStatus.ENABLED) throw new IllegalStateException('cannot enable index ' + indexName + ' in status ' + current)
+}
+`,
+	"REINDEX": `current = statusOf(index)
+if (current != schemaStatus.REGISTERED && current != schemaStatus.ENABLED) {
+    mgmt.rollback()
+    throw new IllegalStateException('cannot reindex index ' + indexName + ' in status ' + current)
+}
+metrics = mgmt.updateIndex(index, schemaAction.REINDEX).get()
+mgmt.commit()
+added = metrics.getCustom(org.janusgraph.graphdb.olap.job.IndexRepairJob.ADDED_RECORDS_COUNT)
+failed = metrics.get(org.janusgraph.diskstorage.keycolumnvalue.scan.ScanMetrics.Metric.FAILURE)
+awaitStatus(schemaStatus.ENABLED)
+`,
+	"DISABLE_INDEX": `if (statusOf(index) != schemaStatus.DISABLED) {
+    mgmt.updateIndex(index, schemaAction.DISABLE_INDEX).get()
+    mgmt.commit()
+    awaitStatus(schemaStatus.DISABLED)
+} else {
+    mgmt.rollback()
+}
+`,
+	"REMOVE_INDEX": `current = statusOf(index)
+if (current != schemaStatus.DISABLED) {
+    mgmt.rollback()
+    throw new IllegalStateException('cannot remove index ' + indexName + ' in status ' + current)
+}
+future = mgmt.updateIndex(index, schemaAction.REMOVE_INDEX)
+if (future == null) {
+    mgmt.rollback()
+    throw new UnsupportedOperationException('index ' + indexName + ' cannot be removed by the management system')
+}
+metrics = future.get()
+mgmt.commit()
+failed = metrics.get(org.janusgraph.diskstorage.keycolumnvalue.scan.ScanMetrics.Metric.FAILURE)
+`,
+}
+
+// JanusgraphIndexJobReconciler reconciles a JanusgraphIndexJob object
+type JanusgraphIndexJobReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=graph.example.com,resources=janusgraphindexjobs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=graph.example.com,resources=janusgraphindexjobs/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=graph.example.com,resources=janusgraphindexjobs/finalizers,verbs=update
+// +kubebuilder:rbac:groups=graph.example.com,resources=janusgraphs,verbs=get;list;watch
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+
+// Reconcile runs the management action of a JanusgraphIndexJob on an index of the referenced Janusgraph.
+// The action is split into steps, e.g. REGISTER_INDEX then REINDEX, and every step is run by its own Job
+// that waits for the index to reach the resulting status. Progress through the steps, the index status
+// and the reason of a failure are recorded in the status of the JanusgraphIndexJob.
+func (r *JanusgraphIndexJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("janusgraphindexjob", req.NamespacedName)
+
+	// Fetch the JanusgraphIndexJob instance
+	indexJob := &graphv1alpha1.JanusgraphIndexJob{}
+	err := r.Get(ctx, req.NamespacedName, indexJob)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// Request object not found, could have been deleted after reconcile request.
+			// Owned objects are automatically garbage collected.
+			log.Info("JanusgraphIndexJob resource not found. Ignoring since object must be deleted")
+			return ctrl.Result{}, nil
+		}
+		// Error reading the object - requeue the request.
+		log.Error(err, "Failed to get JanusgraphIndexJob")
+		return ctrl.Result{}, err
+	}
+
+	// an index job runs only once
+	if indexJob.Status.Phase == indexJobPhaseSucceeded || indexJob.Status.Phase == indexJobPhaseFailed {
+		return ctrl.Result{}, nil
+	}
+
+	steps, ok := indexJobSteps[indexJob.Spec.Action]
+	if !ok {
+		return r.setPhase(ctx, indexJob, indexJobPhaseFailed, "unknown action "+indexJob.Spec.Action)
+	}
+	// the steps done so far belong to the action the job started with
+	if indexJob.Status.Action != "" && indexJob.Status.Action != indexJob.Spec.Action {
+		return r.setPhase(ctx, indexJob, indexJobPhaseFailed,
+			fmt.Sprintf("action changed from %s to %s after the job started", indexJob.Status.Action, indexJob.Spec.Action))
+	}
+	completed := int(indexJob.Status.CompletedSteps)
+	if completed >= len(steps) {
+		return r.setPhase(ctx, indexJob, indexJobPhaseFailed,
+			fmt.Sprintf("%d steps completed but action %s has only %d", completed, indexJob.Spec.Action, len(steps)))
+	}
+	indexJob.Status.Progress = fmt.Sprintf("%d/%d", completed, len(steps))
+	step := steps[completed]
+	name := boundedName(fmt.Sprintf("%s-%d-%s", indexJob.Name, completed, strings.ToLower(strings.Replace(step, "_", "-", -1))), maxJobNameLength)
+
+	job := &batchv1.Job{}
+	err = r.Get(ctx, types.NamespacedName{Name: name, Namespace: indexJob.Namespace}, job)
+	if err != nil && errors.IsNotFound(err) {
+		return r.startStep(ctx, indexJob, name, step)
+	} else if err != nil {
+		log.Error(err, "Failed to get Job")
+		return ctrl.Result{}, err
+	}
+
+	finished, failed := jobFinished(job)
+	if !finished {
+		// the Job is owned by the index job, so its completion triggers the next reconcile
+		return ctrl.Result{}, nil
+	}
+	output, err := gremlinJobOutput(ctx, r.Client, job)
+	if err != nil {
+		log.Error(err, "Failed to read Job output", "Job.Namespace", job.Namespace, "Job.Name", job.Name)
+		return ctrl.Result{}, err
+	}
+	now := metav1.Now()
+	if failed {
+		indexJob.Status.CompletionTime = &now
+		return r.setPhase(ctx, indexJob, indexJobPhaseFailed, step+" failed: "+output)
+	}
+
+	// every step returns the index status and the number of records it added or failed on as a JSON object
+	result := struct {
+		Status string `json:"status"`
+		Added  int64  `json:"added"`
+		Failed int64  `json:"failed"`
+	}{}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		indexJob.Status.CompletionTime = &now
+		return r.setPhase(ctx, indexJob, indexJobPhaseFailed, "Unexpected output of "+step+": "+output)
+	}
+	indexJob.Status.CompletedSteps++
+	indexJob.Status.Progress = fmt.Sprintf("%d/%d", indexJob.Status.CompletedSteps, len(steps))
+	indexJob.Status.IndexStatus = result.Status
+	indexJob.Status.RecordsAdded += result.Added
+	indexJob.Status.RecordsFailed += result.Failed
+	if int(indexJob.Status.CompletedSteps) < len(steps) {
+		// start the next step right away
+		if _, err := r.setPhase(ctx, indexJob, indexJobPhaseRunning, ""); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{Requeue: true}, nil
+	}
+	indexJob.Status.CurrentStep = ""
+	indexJob.Status.CompletionTime = &now
+	return r.setPhase(ctx, indexJob, indexJobPhaseSucceeded, "")
+}
+
+// startStep creates the ConfigMap and Job running a step of the index job once the Janusgraph is ready
+func (r *JanusgraphIndexJobReconciler) startStep(ctx context.Context, indexJob *graphv1alpha1.JanusgraphIndexJob,
+	name string, step string) (ctrl.Result, error) {
+	log := r.Log.WithValues("janusgraphindexjob", types.NamespacedName{Name: indexJob.Name, Namespace: indexJob.Namespace})
+
+	// the management system can only be used once the Gremlin Server of the Janusgraph is serving queries
+	janusgraph := &graphv1beta1.Janusgraph{}
+	err := r.Get(ctx, types.NamespacedName{Name: indexJob.Spec.JanusgraphRef, Namespace: indexJob.Namespace}, janusgraph)
+	if err != nil && errors.IsNotFound(err) {
+		return r.setPhase(ctx, indexJob, indexJobPhasePending, "Janusgraph "+indexJob.Spec.JanusgraphRef+" not found")
+	} else if err != nil {
+		log.Error(err, "Failed to get Janusgraph")
+		return ctrl.Result{}, err
+	}
+	if len(janusgraph.Status.Nodes) == 0 {
+		return r.setPhase(ctx, indexJob, indexJobPhasePending, "Waiting for Janusgraph "+janusgraph.Name+" to become ready")
+	}
+
+	timeout := time.Duration(indexJob.Spec.TimeoutSeconds) * time.Second
+	if timeout == 0 {
+		timeout = time.Hour
+	}
+	configMap := gremlinScriptConfigMap(name, janusgraph, map[string]string{
+		"runner.groovy": gremlinRunnerScript,
+		"script.groovy": indexStepScript(indexJob.Spec.IndexName, step, timeout),
+	})
+	ctrl.SetControllerReference(indexJob, configMap, r.Scheme)
+	err = r.Get(ctx, types.NamespacedName{Name: name, Namespace: indexJob.Namespace}, &corev1.ConfigMap{})
+	if err != nil && errors.IsNotFound(err) {
+		log.Info("Creating a new ConfigMap", "ConfigMap.Namespace", configMap.Namespace, "ConfigMap.Name", configMap.Name)
+		if err = r.Create(ctx, configMap); err != nil {
+			log.Error(err, "Failed to create new ConfigMap", "ConfigMap.Namespace", configMap.Namespace, "ConfigMap.Name", configMap.Name)
+			return ctrl.Result{}, err
+		}
+	} else if err != nil {
+		log.Error(err, "Failed to get ConfigMap")
+		return ctrl.Result{}, err
+	}
+
+	job := gremlinScriptJob(name, janusgraph, name, timeout)
+	ctrl.SetControllerReference(indexJob, job, r.Scheme)
+	log.Info("Creating a new Job", "Job.Namespace", job.Namespace, "Job.Name", job.Name, "Step", step)
+	if err = r.Create(ctx, job); err != nil {
+		log.Error(err, "Failed to create new Job", "Job.Namespace", job.Namespace, "Job.Name", job.Name)
+		return ctrl.Result{}, err
+	}
+	indexJob.Status.Action = indexJob.Spec.Action
+	indexJob.Status.CurrentStep = step
+	return r.setPhase(ctx, indexJob, indexJobPhaseRunning, "")
+}
+
+// indexStepScript renders the management script of a step on the named index. The script returns the
+// status of the index and the number of records added or failed as a JSON object.
+func indexStepScript(indexName string, step string, timeout time.Duration) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "indexName = %s\n", groovyString(indexName))
+	b.WriteString("schemaStatus = org.janusgraph.core.schema.SchemaStatus\n")
+	b.WriteString("schemaAction = org.janusgraph.core.schema.SchemaAction\n")
+	b.WriteString("statusOf = { i -> i.getFieldKeys().collect { i.getIndexStatus(it) }.min() }\n")
+	fmt.Fprintf(&b, "awaitStatus = { s -> org.janusgraph.graphdb.database.management.ManagementSystem.awaitGraphIndexStatus(graph, indexName).status(s).timeout(%d, java.time.temporal.ChronoUnit.SECONDS).call() }\n",
+		int64(timeout.Seconds()))
+	b.WriteString("added = 0L\n")
+	b.WriteString("failed = 0L\n")
+	b.WriteString("mgmt = graph.openManagement()\n")
+	b.WriteString("index = mgmt.getGraphIndex(indexName)\n")
+	b.WriteString("if (index == null) { mgmt.rollback(); throw new IllegalArgumentException('index ' + indexName + ' not found') }\n")
+	b.WriteString(indexStepScripts[step])
+
+	// report the status of the index after the step, removed indexes no longer have one
+	b.WriteString("mgmt = graph.openManagement()\n")
+	b.WriteString("index = mgmt.getGraphIndex(indexName)\n")
+	b.WriteString("result = [status: index == null ? 'REMOVED' : statusOf(index).toString(), added: added, failed: failed]\n")
+	b.WriteString("mgmt.rollback()\n")
+	b.WriteString("groovy.json.JsonOutput.toJson(result)\n")
+	return b.String()
+}
+
+// setPhase records the phase and message of the index job.
+// Pending index jobs are requeued since the Janusgraph they wait for is not watched.
+func (r *JanusgraphIndexJobReconciler) setPhase(ctx context.Context, indexJob *graphv1alpha1.JanusgraphIndexJob,
+	phase string, message string) (ctrl.Result, error) {
+	indexJob.Status.Phase = phase
+	indexJob.Status.Message = message
+	if err := r.Status().Update(ctx, indexJob); err != nil {
+		r.Log.Error(err, "Failed to update JanusgraphIndexJob status", "JanusgraphIndexJob.Namespace", indexJob.Namespace, "JanusgraphIndexJob.Name", indexJob.Name)
+		return ctrl.Result{}, err
+	}
+	if phase == indexJobPhasePending {
+		return ctrl.Result{RequeueAfter: indexJobRequeueDelay}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *JanusgraphIndexJobReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&graphv1alpha1.JanusgraphIndexJob{}).
+		Owns(&batchv1.Job{}).
+		Owns(&corev1.ConfigMap{}).
+		Complete(r)
+}
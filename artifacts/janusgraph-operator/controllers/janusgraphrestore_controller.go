@@ -0,0 +1,301 @@
+/*
+Copyright 2021.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"time"
+
+	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	graphv1alpha1 "github.com/example/janusgraph-operator/api/v1alpha1"
+	graphv1beta1 "github.com/example/janusgraph-operator/api/v1beta1"
+)
+
+const (
+	restorePhasePending   = "Pending"
+	restorePhaseRunning   = "Running"
+	restorePhaseSucceeded = "Succeeded"
+	restorePhaseFailed    = "Failed"
+)
+
+// restoreRequeueDelay is how long to wait before checking on a Janusgraph that is not ready yet
+const restoreRequeueDelay = 30 * time.Second
+
+// JanusgraphRestoreReconciler reconciles a JanusgraphRestore object
+type JanusgraphRestoreReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=graph.example.com,resources=janusgraphrestores,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=graph.example.com,resources=janusgraphrestores/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=graph.example.com,resources=janusgraphrestores/finalizers,verbs=update
+// +kubebuilder:rbac:groups=graph.example.com,resources=janusgraphs,verbs=get;list;watch;create
+// +kubebuilder:rbac:groups=graph.example.com,resources=janusgraphbackups,verbs=get;list;watch
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+
+// Reconcile restores a backup into the Janusgraph referenced by a JanusgraphRestore object.
+// The Janusgraph is created from spec.janusgraph when it does not exist yet. Once it has ready pods
+// a Job loads the GraphSON backup file through its Gremlin Server, like a GraphSON JanusgraphDataLoad.
+// A graph that already has vertices is only restored into when spec.existingGraph is Drop.
+func (r *JanusgraphRestoreReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("janusgraphrestore", req.NamespacedName)
+
+	// Fetch the JanusgraphRestore instance
+	restore := &graphv1alpha1.JanusgraphRestore{}
+	err := r.Get(ctx, req.NamespacedName, restore)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// Request object not found, could have been deleted after reconcile request.
+			// Owned objects are automatically garbage collected.
+			log.Info("JanusgraphRestore resource not found. Ignoring since object must be deleted")
+			return ctrl.Result{}, nil
+		}
+		// Error reading the object - requeue the request.
+		log.Error(err, "Failed to get JanusgraphRestore")
+		return ctrl.Result{}, err
+	}
+
+	// a restore runs only once
+	if restore.Status.Phase == restorePhaseSucceeded || restore.Status.Phase == restorePhaseFailed {
+		return ctrl.Result{}, nil
+	}
+
+	name := boundedName(restore.Name+"-restore", maxJobNameLength)
+	job := &batchv1.Job{}
+	err = r.Get(ctx, types.NamespacedName{Name: name, Namespace: restore.Namespace}, job)
+	if err != nil && errors.IsNotFound(err) {
+		return r.startRestore(ctx, restore, name)
+	} else if err != nil {
+		log.Error(err, "Failed to get Job")
+		return ctrl.Result{}, err
+	}
+
+	finished, failed := jobFinished(job)
+	if !finished {
+		// the Job is owned by the restore, so its completion triggers the next reconcile
+		return ctrl.Result{}, nil
+	}
+	output, err := gremlinJobOutput(ctx, r.Client, job)
+	if err != nil {
+		log.Error(err, "Failed to read Job output", "Job.Namespace", job.Namespace, "Job.Name", job.Name)
+		return ctrl.Result{}, err
+	}
+	now := metav1.Now()
+	restore.Status.CompletionTime = &now
+	if failed {
+		if output == "" {
+			// the pod was stopped before the loader could report, e.g. it was evicted or ran out of memory
+			output = "The restore Job " + job.Name + " failed without an error, see the events of its pod"
+		}
+		return r.setPhase(ctx, restore, restorePhaseFailed, output)
+	}
+
+	// the loader returns the number of vertices and edges it added as a JSON object
+	counts := struct {
+		Vertices int64 `json:"vertices"`
+		Edges    int64 `json:"edges"`
+	}{}
+	if err := json.Unmarshal([]byte(output), &counts); err != nil {
+		return r.setPhase(ctx, restore, restorePhaseFailed, "Unexpected output of the loader: "+output)
+	}
+	restore.Status.Vertices = counts.Vertices
+	restore.Status.Edges = counts.Edges
+	return r.setPhase(ctx, restore, restorePhaseSucceeded, "")
+}
+
+// startRestore resolves the backup file, creates the target Janusgraph if needed, and creates the
+// ConfigMap and Job of the restore once the Janusgraph is ready
+func (r *JanusgraphRestoreReconciler) startRestore(ctx context.Context, restore *graphv1alpha1.JanusgraphRestore,
+	name string) (ctrl.Result, error) {
+	log := r.Log.WithValues("janusgraphrestore", types.NamespacedName{Name: restore.Name, Namespace: restore.Namespace})
+
+	storage, file, result, err := r.backupFile(ctx, restore)
+	if storage == nil {
+		return result, err
+	}
+	restore.Status.File = file
+
+	janusgraph := &graphv1beta1.Janusgraph{}
+	err = r.Get(ctx, types.NamespacedName{Name: restore.Spec.JanusgraphRef, Namespace: restore.Namespace}, janusgraph)
+	if err != nil && errors.IsNotFound(err) {
+		if restore.Spec.Janusgraph == nil {
+			return r.setPhase(ctx, restore, restorePhasePending, "Janusgraph "+restore.Spec.JanusgraphRef+" not found")
+		}
+		// the new Janusgraph is not owned by the restore, so it outlives it
+		spec := &graphv1alpha1.Janusgraph{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      restore.Spec.JanusgraphRef,
+				Namespace: restore.Namespace,
+			},
+			Spec: *restore.Spec.Janusgraph,
+		}
+		//the restore embeds the v1alpha1 spec, the Janusgraph is created in the storage version
+		janusgraph = &graphv1beta1.Janusgraph{}
+		if err = spec.ConvertTo(janusgraph); err != nil {
+			return ctrl.Result{}, err
+		}
+		log.Info("Creating a new Janusgraph", "Janusgraph.Namespace", janusgraph.Namespace, "Janusgraph.Name", janusgraph.Name)
+		if err = r.Create(ctx, janusgraph); err != nil {
+			log.Error(err, "Failed to create new Janusgraph", "Janusgraph.Namespace", janusgraph.Namespace, "Janusgraph.Name", janusgraph.Name)
+			return ctrl.Result{}, err
+		}
+	} else if err != nil {
+		log.Error(err, "Failed to get Janusgraph")
+		return ctrl.Result{}, err
+	}
+	if len(janusgraph.Status.Nodes) == 0 {
+		return r.setPhase(ctx, restore, restorePhasePending, "Waiting for Janusgraph "+janusgraph.Name+" to become ready")
+	}
+
+	volume, dir, err := backupVolume(storage)
+	if err != nil {
+		return r.setPhase(ctx, restore, restorePhaseFailed, err.Error())
+	}
+
+	configMap := gremlinScriptConfigMap(name, janusgraph, map[string]string{"runner.groovy": dataLoadRunnerScript})
+	ctrl.SetControllerReference(restore, configMap, r.Scheme)
+	err = r.Get(ctx, types.NamespacedName{Name: name, Namespace: restore.Namespace}, &corev1.ConfigMap{})
+	if err != nil && errors.IsNotFound(err) {
+		log.Info("Creating a new ConfigMap", "ConfigMap.Namespace", configMap.Namespace, "ConfigMap.Name", configMap.Name)
+		if err = r.Create(ctx, configMap); err != nil {
+			log.Error(err, "Failed to create new ConfigMap", "ConfigMap.Namespace", configMap.Namespace, "ConfigMap.Name", configMap.Name)
+			return ctrl.Result{}, err
+		}
+	} else if err != nil {
+		log.Error(err, "Failed to get ConfigMap")
+		return ctrl.Result{}, err
+	}
+
+	job := r.jobForRestore(restore, janusgraph, name, storage, volume, path.Join(dir, file))
+	log.Info("Creating a new Job", "Job.Namespace", job.Namespace, "Job.Name", job.Name)
+	if err = r.Create(ctx, job); err != nil {
+		log.Error(err, "Failed to create new Job", "Job.Namespace", job.Namespace, "Job.Name", job.Name)
+		return ctrl.Result{}, err
+	}
+	return r.setPhase(ctx, restore, restorePhaseRunning, "")
+}
+
+// backupFile returns the storage and name of the backup file to restore, taken from the referenced
+// JanusgraphBackup or from the restore itself. When the file cannot be resolved the storage is nil
+// and the phase of the restore has been updated.
+func (r *JanusgraphRestoreReconciler) backupFile(ctx context.Context,
+	restore *graphv1alpha1.JanusgraphRestore) (*graphv1alpha1.BackupStorage, string, ctrl.Result, error) {
+	if restore.Spec.BackupRef == "" {
+		if restore.Spec.Storage == nil || restore.Spec.File == "" {
+			result, err := r.setPhase(ctx, restore, restorePhaseFailed, "storage and file must be set when backupRef is empty")
+			return nil, "", result, err
+		}
+		return restore.Spec.Storage, restore.Spec.File, ctrl.Result{}, nil
+	}
+
+	backup := &graphv1alpha1.JanusgraphBackup{}
+	err := r.Get(ctx, types.NamespacedName{Name: restore.Spec.BackupRef, Namespace: restore.Namespace}, backup)
+	if err != nil && errors.IsNotFound(err) {
+		result, err := r.setPhase(ctx, restore, restorePhasePending, "JanusgraphBackup "+restore.Spec.BackupRef+" not found")
+		return nil, "", result, err
+	} else if err != nil {
+		r.Log.Error(err, "Failed to get JanusgraphBackup")
+		return nil, "", ctrl.Result{}, err
+	}
+	file := restore.Spec.File
+	if file == "" {
+		if backup.Status.LastBackup == nil {
+			result, err := r.setPhase(ctx, restore, restorePhasePending, "Waiting for JanusgraphBackup "+backup.Name+" to take a backup")
+			return nil, "", result, err
+		}
+		file = backup.Status.LastBackup.File
+	}
+	return &backup.Spec.Storage, file, ctrl.Result{}, nil
+}
+
+// jobForRestore returns the Job loading a backup file into a Janusgraph.
+// Backups kept in S3 are first downloaded into an emptyDir by the MinIO client running as an init container.
+func (r *JanusgraphRestoreReconciler) jobForRestore(restore *graphv1alpha1.JanusgraphRestore, jg *graphv1beta1.Janusgraph,
+	name string, storage *graphv1alpha1.BackupStorage, volume corev1.Volume, dataFile string) *batchv1.Job {
+	timeout := time.Duration(restore.Spec.TimeoutSeconds) * time.Second
+	if timeout == 0 {
+		timeout = 10 * time.Minute
+	}
+
+	//restoring into a graph that has data would duplicate it, so it is rejected unless dropping is asked for
+	existingGraph := restore.Spec.ExistingGraph
+	if existingGraph == "" {
+		existingGraph = "Reject"
+	}
+
+	job := gremlinScriptJob(name, jg, name, timeout)
+	//a retry would find the vertices written before the failure and be rejected as a restore into a graph
+	//that is not empty, so the Job is not retried and its first failure is recorded in the status
+	backoffLimit := int32(0)
+	job.Spec.BackoffLimit = &backoffLimit
+	podSpec := &job.Spec.Template.Spec
+	podSpec.Volumes = append(podSpec.Volumes, volume)
+	gremlin := &podSpec.Containers[0]
+	gremlin.VolumeMounts = append(gremlin.VolumeMounts, corev1.VolumeMount{Name: volume.Name, MountPath: backupPath, ReadOnly: true})
+	gremlin.Env = append(gremlin.Env,
+		corev1.EnvVar{Name: "DATA_FILE", Value: dataFile},
+		corev1.EnvVar{Name: "DATA_FORMAT", Value: "GraphSON"},
+		corev1.EnvVar{Name: "BATCH_SIZE", Value: "500"},
+		corev1.EnvVar{Name: "EXISTING_GRAPH", Value: existingGraph},
+	)
+
+	if s3 := storage.S3; s3 != nil {
+		download := s3ClientContainer("download", s3, volume.Name,
+			`mc cp "backup/$S3_BUCKET/${S3_PREFIX}$BACKUP_FILE" "`+dataFile+`"`)
+		download.Env = append(download.Env, corev1.EnvVar{Name: "BACKUP_FILE", Value: path.Base(dataFile)})
+		podSpec.InitContainers = []corev1.Container{download}
+	}
+	ctrl.SetControllerReference(restore, job, r.Scheme)
+	return job
+}
+
+// setPhase records the phase and message of the restore.
+// Pending restores are requeued since the Janusgraph and backup they wait for are not watched.
+func (r *JanusgraphRestoreReconciler) setPhase(ctx context.Context, restore *graphv1alpha1.JanusgraphRestore,
+	phase string, message string) (ctrl.Result, error) {
+	restore.Status.Phase = phase
+	restore.Status.Message = message
+	if err := r.Status().Update(ctx, restore); err != nil {
+		r.Log.Error(err, "Failed to update JanusgraphRestore status", "JanusgraphRestore.Namespace", restore.Namespace, "JanusgraphRestore.Name", restore.Name)
+		return ctrl.Result{}, err
+	}
+	if phase == restorePhasePending {
+		return ctrl.Result{RequeueAfter: restoreRequeueDelay}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *JanusgraphRestoreReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&graphv1alpha1.JanusgraphRestore{}).
+		Owns(&batchv1.Job{}).
+		Owns(&corev1.ConfigMap{}).
+		Complete(r)
+}
@@ -0,0 +1,280 @@
+/*
+Copyright 2021.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	graphv1alpha1 "github.com/example/janusgraph-operator/api/v1alpha1"
+	graphv1beta1 "github.com/example/janusgraph-operator/api/v1beta1"
+)
+
+const (
+	schemaPhasePending  = "Pending"
+	schemaPhaseApplying = "Applying"
+	schemaPhaseApplied  = "Applied"
+	schemaPhaseFailed   = "Failed"
+)
+
+// schemaRequeueDelay is how long to wait before checking on a Janusgraph that is not ready yet
+const schemaRequeueDelay = 30 * time.Second
+
+// schemaScriptTimeout is how long the management script may run on the Gremlin Server
+const schemaScriptTimeout = 5 * time.Minute
+
+// JanusgraphSchemaReconciler reconciles a JanusgraphSchema object
+type JanusgraphSchemaReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=graph.example.com,resources=janusgraphschemas,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=graph.example.com,resources=janusgraphschemas/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=graph.example.com,resources=janusgraphschemas/finalizers,verbs=update
+// +kubebuilder:rbac:groups=graph.example.com,resources=janusgraphs,verbs=get;list;watch
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+
+// Reconcile applies the schema of a JanusgraphSchema object to the referenced Janusgraph.
+// Every generation of the schema is rendered into a Groovy script using the JanusGraph management API,
+// which a Job submits to the Gremlin Server. Once the Job completes the applied revision and the
+// status of every index are recorded in the status of the JanusgraphSchema.
+func (r *JanusgraphSchemaReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("janusgraphschema", req.NamespacedName)
+
+	// Fetch the JanusgraphSchema instance
+	schema := &graphv1alpha1.JanusgraphSchema{}
+	err := r.Get(ctx, req.NamespacedName, schema)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// Request object not found, could have been deleted after reconcile request.
+			// Owned objects are automatically garbage collected.
+			log.Info("JanusgraphSchema resource not found. Ignoring since object must be deleted")
+			return ctrl.Result{}, nil
+		}
+		// Error reading the object - requeue the request.
+		log.Error(err, "Failed to get JanusgraphSchema")
+		return ctrl.Result{}, err
+	}
+
+	// this revision of the schema has already been applied
+	if schema.Status.AppliedRevision == schema.Generation && schema.Status.Phase == schemaPhaseApplied {
+		return ctrl.Result{}, nil
+	}
+
+	// the schema can only be applied once the Gremlin Server of the Janusgraph is serving queries
+	janusgraph := &graphv1beta1.Janusgraph{}
+	err = r.Get(ctx, types.NamespacedName{Name: schema.Spec.JanusgraphRef, Namespace: schema.Namespace}, janusgraph)
+	if err != nil && errors.IsNotFound(err) {
+		return r.setPhase(ctx, schema, schemaPhasePending, "Janusgraph "+schema.Spec.JanusgraphRef+" not found", nil)
+	} else if err != nil {
+		log.Error(err, "Failed to get Janusgraph")
+		return ctrl.Result{}, err
+	}
+	if len(janusgraph.Status.Nodes) == 0 {
+		return r.setPhase(ctx, schema, schemaPhasePending, "Waiting for Janusgraph "+janusgraph.Name+" to become ready", nil)
+	}
+
+	// every revision of the schema is applied by its own Job and ConfigMap
+	name := boundedName(fmt.Sprintf("%s-schema-%d", schema.Name, schema.Generation), maxJobNameLength)
+
+	configMap := gremlinScriptConfigMap(name, janusgraph, map[string]string{
+		"runner.groovy": gremlinRunnerScript,
+		"script.groovy": schemaScript(&schema.Spec),
+	})
+	ctrl.SetControllerReference(schema, configMap, r.Scheme)
+	err = r.Get(ctx, types.NamespacedName{Name: name, Namespace: schema.Namespace}, &corev1.ConfigMap{})
+	if err != nil && errors.IsNotFound(err) {
+		log.Info("Creating a new ConfigMap", "ConfigMap.Namespace", configMap.Namespace, "ConfigMap.Name", configMap.Name)
+		if err = r.Create(ctx, configMap); err != nil {
+			log.Error(err, "Failed to create new ConfigMap", "ConfigMap.Namespace", configMap.Namespace, "ConfigMap.Name", configMap.Name)
+			return ctrl.Result{}, err
+		}
+	} else if err != nil {
+		log.Error(err, "Failed to get ConfigMap")
+		return ctrl.Result{}, err
+	}
+
+	job := &batchv1.Job{}
+	err = r.Get(ctx, types.NamespacedName{Name: name, Namespace: schema.Namespace}, job)
+	if err != nil && errors.IsNotFound(err) {
+		job = gremlinScriptJob(name, janusgraph, configMap.Name, schemaScriptTimeout)
+		ctrl.SetControllerReference(schema, job, r.Scheme)
+		log.Info("Creating a new Job", "Job.Namespace", job.Namespace, "Job.Name", job.Name)
+		if err = r.Create(ctx, job); err != nil {
+			log.Error(err, "Failed to create new Job", "Job.Namespace", job.Namespace, "Job.Name", job.Name)
+			return ctrl.Result{}, err
+		}
+		return r.setPhase(ctx, schema, schemaPhaseApplying, "Applying revision "+fmt.Sprint(schema.Generation), nil)
+	} else if err != nil {
+		log.Error(err, "Failed to get Job")
+		return ctrl.Result{}, err
+	}
+
+	finished, failed := jobFinished(job)
+	if !finished {
+		// the Job is owned by the schema, so its completion triggers the next reconcile
+		return ctrl.Result{}, nil
+	}
+	output, err := gremlinJobOutput(ctx, r.Client, job)
+	if err != nil {
+		log.Error(err, "Failed to read Job output", "Job.Namespace", job.Namespace, "Job.Name", job.Name)
+		return ctrl.Result{}, err
+	}
+	if failed {
+		return r.setPhase(ctx, schema, schemaPhaseFailed, output, schema.Status.Indexes)
+	}
+
+	// the management script returns the status of every index as a JSON object
+	indexes := map[string]string{}
+	if err := json.Unmarshal([]byte(output), &indexes); err != nil {
+		return r.setPhase(ctx, schema, schemaPhaseFailed, "Unexpected output of the management script: "+output, schema.Status.Indexes)
+	}
+	schema.Status.AppliedRevision = schema.Generation
+	return r.setPhase(ctx, schema, schemaPhaseApplied, "", indexStatuses(indexes))
+}
+
+// setPhase records the phase, message and index statuses of the schema.
+// Pending schemas are requeued since the Janusgraph they wait for is not watched.
+func (r *JanusgraphSchemaReconciler) setPhase(ctx context.Context, schema *graphv1alpha1.JanusgraphSchema,
+	phase string, message string, indexes []graphv1alpha1.IndexStatus) (ctrl.Result, error) {
+	schema.Status.Phase = phase
+	schema.Status.Message = message
+	schema.Status.Indexes = indexes
+	if err := r.Status().Update(ctx, schema); err != nil {
+		r.Log.Error(err, "Failed to update JanusgraphSchema status", "JanusgraphSchema.Namespace", schema.Namespace, "JanusgraphSchema.Name", schema.Name)
+		return ctrl.Result{}, err
+	}
+	if phase == schemaPhasePending {
+		return ctrl.Result{RequeueAfter: schemaRequeueDelay}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *JanusgraphSchemaReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&graphv1alpha1.JanusgraphSchema{}).
+		Owns(&batchv1.Job{}).
+		Owns(&corev1.ConfigMap{}).
+		Complete(r)
+}
+
+// schemaScript renders the schema into a Groovy script for the JanusGraph management API.
+// Elements that already exist are skipped, so applying a revision twice is harmless.
+// The script returns a JSON object mapping every index name to its status.
+func schemaScript(spec *graphv1alpha1.JanusgraphSchemaSpec) string {
+	var b strings.Builder
+	b.WriteString("mgmt = graph.openManagement()\n")
+	for _, key := range spec.PropertyKeys {
+		cardinality := key.Cardinality
+		if cardinality == "" {
+			cardinality = "SINGLE"
+		}
+		fmt.Fprintf(&b, "if (!mgmt.containsPropertyKey(%s)) { mgmt.makePropertyKey(%s).dataType(%s.class).cardinality(org.janusgraph.core.Cardinality.%s).make() }\n",
+			groovyString(key.Name), groovyString(key.Name), key.DataType, cardinality)
+	}
+	for _, label := range spec.VertexLabels {
+		fmt.Fprintf(&b, "if (!mgmt.containsVertexLabel(%s)) { mgmt.makeVertexLabel(%s).make() }\n",
+			groovyString(label.Name), groovyString(label.Name))
+	}
+	for _, label := range spec.EdgeLabels {
+		multiplicity := label.Multiplicity
+		if multiplicity == "" {
+			multiplicity = "MULTI"
+		}
+		fmt.Fprintf(&b, "if (!mgmt.containsEdgeLabel(%s)) { mgmt.makeEdgeLabel(%s).multiplicity(org.janusgraph.core.Multiplicity.%s).make() }\n",
+			groovyString(label.Name), groovyString(label.Name), multiplicity)
+	}
+
+	var indexNames []string
+	for _, index := range spec.CompositeIndexes {
+		build := ".buildCompositeIndex()"
+		if index.Unique {
+			build = ".unique()" + build
+		}
+		fmt.Fprintf(&b, "if (!mgmt.containsGraphIndex(%s)) { mgmt.buildIndex(%s, %s.class)%s%s }\n",
+			groovyString(index.Name), groovyString(index.Name), elementClass(index.ElementType), indexKeys(index.Keys), build)
+		indexNames = append(indexNames, groovyString(index.Name))
+	}
+	for _, index := range spec.MixedIndexes {
+		backend := index.Backend
+		if backend == "" {
+			backend = "search"
+		}
+		fmt.Fprintf(&b, "if (!mgmt.containsGraphIndex(%s)) { mgmt.buildIndex(%s, %s.class)%s.buildMixedIndex(%s) }\n",
+			groovyString(index.Name), groovyString(index.Name), elementClass(index.ElementType), indexKeys(index.Keys), groovyString(backend))
+		indexNames = append(indexNames, groovyString(index.Name))
+	}
+	b.WriteString("mgmt.commit()\n")
+
+	// report the least advanced status of the keys of every index
+	b.WriteString("mgmt = graph.openManagement()\n")
+	b.WriteString("indexes = [:]\n")
+	fmt.Fprintf(&b, "[%s].each { name ->\n", strings.Join(indexNames, ", "))
+	b.WriteString("    index = mgmt.getGraphIndex(name)\n")
+	b.WriteString("    indexes[name] = index.getFieldKeys().collect { index.getIndexStatus(it) }.min().toString()\n")
+	b.WriteString("}\n")
+	b.WriteString("mgmt.rollback()\n")
+	b.WriteString("groovy.json.JsonOutput.toJson(indexes)\n")
+	return b.String()
+}
+
+// indexKeys renders the addKey calls of an index definition
+func indexKeys(keys []string) string {
+	var b strings.Builder
+	for _, key := range keys {
+		fmt.Fprintf(&b, ".addKey(mgmt.getPropertyKey(%s))", groovyString(key))
+	}
+	return b.String()
+}
+
+// elementClass returns the TinkerPop class of the elements an index applies to
+func elementClass(elementType string) string {
+	if elementType == "Edge" {
+		return "Edge"
+	}
+	return "Vertex"
+}
+
+// groovyString quotes s as a single quoted Groovy string literal
+func groovyString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+// indexStatuses converts the index statuses returned by the management script, sorted by name
+func indexStatuses(indexes map[string]string) []graphv1alpha1.IndexStatus {
+	var statuses []graphv1alpha1.IndexStatus
+	for name, status := range indexes {
+		statuses = append(statuses, graphv1alpha1.IndexStatus{Name: name, Status: status})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
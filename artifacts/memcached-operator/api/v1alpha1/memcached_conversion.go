@@ -0,0 +1,93 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"encoding/json"
+
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/example/memcached-operator/api/v1beta1"
+)
+
+// specAnnotation keeps the fields of a v1beta1 Memcached that v1alpha1 has no place for,
+// so they are not lost when a v1alpha1 client reads and writes back the object
+const specAnnotation = "cache.example.com/v1beta1-spec"
+
+// beta1Spec are the fields of the v1beta1 MemcachedSpec kept in specAnnotation
+type beta1Spec struct {
+	Image       string `json:"image,omitempty"`
+	CacheSizeMB int32  `json:"cacheSizeMB,omitempty"`
+}
+
+// ConvertTo converts this Memcached to the Hub version (v1beta1)
+func (src *Memcached) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1beta1.Memcached)
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+
+	dst.Spec.Replicas = src.Spec.Size
+	dst.Spec.Image = ""
+	dst.Spec.CacheSizeMB = 0
+	if value, ok := dst.Annotations[specAnnotation]; ok {
+		spec := beta1Spec{}
+		if err := json.Unmarshal([]byte(value), &spec); err != nil {
+			return err
+		}
+		dst.Spec.Image = spec.Image
+		dst.Spec.CacheSizeMB = spec.CacheSizeMB
+		delete(dst.Annotations, specAnnotation)
+		if len(dst.Annotations) == 0 {
+			dst.Annotations = nil
+		}
+	}
+	dst.Spec.Cleanup = v1beta1.MemcachedCleanupSpec(src.Spec.Cleanup)
+
+	dst.Status = v1beta1.MemcachedStatus{
+		Nodes:   src.Status.Nodes,
+		Cleanup: (*v1beta1.MemcachedCleanupStatus)(src.Status.Cleanup),
+	}
+	return nil
+}
+
+// ConvertFrom converts from the Hub version (v1beta1) to this version
+func (dst *Memcached) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1beta1.Memcached)
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+
+	dst.Spec.Size = src.Spec.Replicas
+	spec := beta1Spec{
+		Image:       src.Spec.Image,
+		CacheSizeMB: src.Spec.CacheSizeMB,
+	}
+	if spec != (beta1Spec{}) {
+		value, err := json.Marshal(spec)
+		if err != nil {
+			return err
+		}
+		if dst.Annotations == nil {
+			dst.Annotations = map[string]string{}
+		}
+		dst.Annotations[specAnnotation] = string(value)
+	}
+	dst.Spec.Cleanup = MemcachedCleanupSpec(src.Spec.Cleanup)
+
+	dst.Status = MemcachedStatus{
+		Nodes:   src.Status.Nodes,
+		Cleanup: (*MemcachedCleanupStatus)(src.Status.Cleanup),
+	}
+	return nil
+}
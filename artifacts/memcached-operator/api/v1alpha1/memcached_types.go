@@ -28,8 +28,28 @@ type MemcachedSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 
-	// Foo is an example field of Memcached. Edit Memcached_types.go to remove/update
-	Size int32 `json:"size"`
+	// Size is the number of memcached pods. Defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Size int32 `json:"size,omitempty"`
+
+	// Cleanup configures what is done before a deleted Memcached is released
+	// +optional
+	Cleanup MemcachedCleanupSpec `json:"cleanup,omitempty"`
+}
+
+// MemcachedCleanupSpec defines the cleanup run by the finalizer of a Memcached when it is deleted
+type MemcachedCleanupSpec struct {
+	// Flush sends flush_all to every memcached pod, so clients still connected during the
+	// deletion do not read stale entries
+	// +optional
+	Flush bool `json:"flush,omitempty"`
+
+	// TimeoutSeconds is how long the cleanup may take before the Memcached is released anyway.
+	// Defaults to 60.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
 }
 
 // MemcachedStatus defines the observed state of Memcached
@@ -37,6 +57,21 @@ type MemcachedStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 	Nodes []string `json:"nodes"`
+
+	// Cleanup is the progress of the cleanup of a deleted Memcached
+	// +optional
+	Cleanup *MemcachedCleanupStatus `json:"cleanup,omitempty"`
+}
+
+// MemcachedCleanupStatus defines the state of the cleanup of a deleted Memcached
+type MemcachedCleanupStatus struct {
+	// Phase is Running while the cleanup is in progress, Failed when a step failed and the cleanup
+	// waits for the timeout, or TimedOut when the Memcached was released before the cleanup finished
+	Phase string `json:"phase"`
+
+	// Message explains the phase, e.g. the pods that could not be flushed
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 // +kubebuilder:object:root=true
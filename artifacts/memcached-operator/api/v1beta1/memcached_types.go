@@ -0,0 +1,107 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// MemcachedSpec defines the desired state of Memcached
+type MemcachedSpec struct {
+	// Replicas is the number of memcached pods. Defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// Image is the memcached image. Defaults to memcached:1.4.36-alpine.
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// CacheSizeMB is the memory each memcached pod uses for items, in megabytes. Defaults to 64.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	CacheSizeMB int32 `json:"cacheSizeMB,omitempty"`
+
+	// Cleanup configures what is done before a deleted Memcached is released
+	// +optional
+	Cleanup MemcachedCleanupSpec `json:"cleanup,omitempty"`
+}
+
+// MemcachedCleanupSpec defines the cleanup run by the finalizer of a Memcached when it is deleted
+type MemcachedCleanupSpec struct {
+	// Flush sends flush_all to every memcached pod, so clients still connected during the
+	// deletion do not read stale entries
+	// +optional
+	Flush bool `json:"flush,omitempty"`
+
+	// TimeoutSeconds is how long the cleanup may take before the Memcached is released anyway.
+	// Defaults to 60.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+}
+
+// MemcachedStatus defines the observed state of Memcached
+type MemcachedStatus struct {
+	// Nodes are the names of the memcached pods
+	// +optional
+	Nodes []string `json:"nodes,omitempty"`
+
+	// Cleanup is the progress of the cleanup of a deleted Memcached
+	// +optional
+	Cleanup *MemcachedCleanupStatus `json:"cleanup,omitempty"`
+}
+
+// MemcachedCleanupStatus defines the state of the cleanup of a deleted Memcached
+type MemcachedCleanupStatus struct {
+	// Phase is Running while the cleanup is in progress, Failed when a step failed and the cleanup
+	// waits for the timeout, or TimedOut when the Memcached was released before the cleanup finished
+	Phase string `json:"phase"`
+
+	// Message explains the phase, e.g. the pods that could not be flushed
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:storageversion
+// +kubebuilder:printcolumn:name="Replicas",type=integer,JSONPath=`.spec.replicas`
+// +kubebuilder:printcolumn:name="Image",type=string,JSONPath=`.spec.image`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// Memcached is the Schema for the memcacheds API
+type Memcached struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   MemcachedSpec   `json:"spec,omitempty"`
+	Status MemcachedStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// MemcachedList contains a list of Memcached
+type MemcachedList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Memcached `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Memcached{}, &MemcachedList{})
+}
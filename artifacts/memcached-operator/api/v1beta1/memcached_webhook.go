@@ -0,0 +1,182 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var memcachedlog = logf.Log.WithName("memcached-resource")
+
+// DefaultMemcachedImage is the memcached image used when Spec.Image is empty
+const DefaultMemcachedImage = "memcached:1.4.36-alpine"
+
+// DefaultMemcachedCacheSizeMB is the cache size used when Spec.CacheSizeMB is not set
+const DefaultMemcachedCacheSizeMB = 64
+
+// releasePattern matches the numeric part of a release tag, e.g. 1.4.36 in 1.4.36-alpine
+var releasePattern = regexp.MustCompile(`^v?(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// SetupWebhookWithManager registers the defaulting, validating and conversion webhooks of Memcached with the manager.
+// Requests for v1alpha1 objects are converted to v1beta1 before they reach the defaulting and validating webhooks.
+func (r *Memcached) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// Hub marks v1beta1 as the version the other versions of Memcached are converted to and from
+func (*Memcached) Hub() {}
+
+// +kubebuilder:webhook:path=/mutate-cache-example-com-v1beta1-memcached,mutating=true,failurePolicy=fail,sideEffects=None,groups=cache.example.com,resources=memcacheds,verbs=create;update,versions=v1beta1,name=mmemcached.kb.io,admissionReviewVersions={v1,v1beta1}
+
+var _ webhook.Defaulter = &Memcached{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type
+func (r *Memcached) Default() {
+	memcachedlog.Info("default", "name", r.Name)
+
+	if r.Spec.Replicas == 0 {
+		r.Spec.Replicas = 1
+	}
+	if r.Spec.Image == "" {
+		r.Spec.Image = DefaultMemcachedImage
+	}
+	if r.Spec.CacheSizeMB == 0 {
+		r.Spec.CacheSizeMB = DefaultMemcachedCacheSizeMB
+	}
+}
+
+// +kubebuilder:webhook:path=/validate-cache-example-com-v1beta1-memcached,mutating=false,failurePolicy=fail,sideEffects=None,groups=cache.example.com,resources=memcacheds,verbs=create;update,versions=v1beta1,name=vmemcached.kb.io,admissionReviewVersions={v1,v1beta1}
+
+var _ webhook.Validator = &Memcached{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *Memcached) ValidateCreate() error {
+	memcachedlog.Info("validate create", "name", r.Name)
+
+	return r.invalid(r.validateSpec())
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *Memcached) ValidateUpdate(old runtime.Object) error {
+	memcachedlog.Info("validate update", "name", r.Name)
+
+	allErrs := r.validateSpec()
+	if oldMemcached, ok := old.(*Memcached); ok {
+		allErrs = append(allErrs, r.validateImageUpdate(oldMemcached)...)
+	}
+	return r.invalid(allErrs)
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *Memcached) ValidateDelete() error {
+	memcachedlog.Info("validate delete", "name", r.Name)
+
+	return nil
+}
+
+//validateSpec rejects a Memcached without any pods or cache memory
+func (r *Memcached) validateSpec() field.ErrorList {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+	if r.Spec.Replicas < 1 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("replicas"), r.Spec.Replicas, "must be at least 1"))
+	}
+	if r.Spec.CacheSizeMB < 1 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("cacheSizeMB"), r.Spec.CacheSizeMB, "must be at least 1"))
+	}
+	return allErrs
+}
+
+//validateImageUpdate rejects downgrades of the memcached release of an image repository.
+//A change of repository and tags that are not release numbers, e.g. latest, are not compared.
+func (r *Memcached) validateImageUpdate(old *Memcached) field.ErrorList {
+	var allErrs field.ErrorList
+	repository, tag := splitImage(r.Spec.Image)
+	oldRepository, oldTag := splitImage(old.Spec.Image)
+	if repository != oldRepository {
+		return allErrs
+	}
+	version, ok := releaseVersion(tag)
+	if !ok {
+		return allErrs
+	}
+	oldVersion, ok := releaseVersion(oldTag)
+	if !ok {
+		return allErrs
+	}
+	for i := range version {
+		if version[i] > oldVersion[i] {
+			break
+		}
+		if version[i] < oldVersion[i] {
+			allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "image"),
+				"downgrading from "+old.Spec.Image+" to "+r.Spec.Image+" is not supported"))
+			break
+		}
+	}
+	return allErrs
+}
+
+//invalid returns an Invalid error for the given field errors, or nil if there are none
+func (r *Memcached) invalid(allErrs field.ErrorList) error {
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(schema.GroupKind{Group: GroupVersion.Group, Kind: "Memcached"}, r.Name, allErrs)
+}
+
+//splitImage splits an image into its repository and tag. The port of a registry is not taken for a tag.
+func splitImage(image string) (string, string) {
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i:], "/") {
+		return image, ""
+	}
+	return image[:i], image[i+1:]
+}
+
+//releaseVersion returns the major, minor and patch numbers of a release tag, e.g. [1 4 36] for 1.4.36-alpine
+func releaseVersion(version string) ([3]int, bool) {
+	var release [3]int
+	match := releasePattern.FindStringSubmatch(strings.TrimSpace(version))
+	if match == nil {
+		return release, false
+	}
+	for i := range release {
+		if match[i+1] == "" {
+			continue
+		}
+		number, err := strconv.Atoi(match[i+1])
+		if err != nil {
+			return release, false
+		}
+		release[i] = number
+	}
+	return release, true
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	cachev1alpha1 "github.com/example/memcached-operator/api/v1alpha1"
+	cachev1beta1 "github.com/example/memcached-operator/api/v1beta1"
+)
+
+var _ = Describe("Memcached webhooks", func() {
+	const (
+		timeout  = 10 * time.Second
+		interval = 250 * time.Millisecond
+	)
+
+	var (
+		ctx context.Context
+		key types.NamespacedName
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "memcached-webhook-test-"}}
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+		key = types.NamespacedName{Name: "memcached-sample", Namespace: ns.Name}
+	})
+
+	It("serves a v1alpha1 Memcached converted to v1beta1", func() {
+		memcached := &cachev1alpha1.Memcached{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+			Spec:       cachev1alpha1.MemcachedSpec{Size: 3},
+		}
+		Expect(k8sClient.Create(ctx, memcached)).To(Succeed())
+
+		// the fields v1alpha1 has no place for are set by the defaulting webhook of v1beta1
+		converted := &cachev1beta1.Memcached{}
+		Eventually(func() error {
+			return k8sClient.Get(ctx, key, converted)
+		}, timeout, interval).Should(Succeed())
+		Expect(converted.Spec.Replicas).To(Equal(int32(3)))
+		Expect(converted.Spec.Image).To(Equal(cachev1beta1.DefaultMemcachedImage))
+		Expect(converted.Spec.CacheSizeMB).To(Equal(int32(cachev1beta1.DefaultMemcachedCacheSizeMB)))
+		Expect(converted.Annotations).To(BeEmpty())
+
+		// and back, for the clients still using v1alpha1
+		Expect(k8sClient.Get(ctx, key, memcached)).To(Succeed())
+		Expect(memcached.Spec.Size).To(Equal(int32(3)))
+	})
+
+	It("rejects a v1alpha1 Memcached with a negative size", func() {
+		memcached := &cachev1alpha1.Memcached{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+			Spec:       cachev1alpha1.MemcachedSpec{Size: -3},
+		}
+		err := k8sClient.Create(ctx, memcached)
+		Expect(apierrors.IsInvalid(err)).To(BeTrue(), "expected an invalid error, got %v", err)
+		Expect(err.Error()).To(ContainSubstring("spec.size"))
+	})
+})
@@ -0,0 +1,54 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+// update rewrites the golden files in testdata with the objects built by the tests.
+// Run go test ./controllers/ -run Golden -update after an intended change of an object.
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// expectGolden compares the YAML of obj with the golden file testdata/<name>.yaml
+func expectGolden(t *testing.T, name string, obj interface{}) {
+	t.Helper()
+	got, err := yaml.Marshal(obj)
+	if err != nil {
+		t.Fatalf("Failed to marshal %s: %v", name, err)
+	}
+	path := filepath.Join("testdata", name+".yaml")
+	if *update {
+		if err = ioutil.WriteFile(path, got, 0644); err != nil {
+			t.Fatalf("Failed to update %s: %v", path, err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s, run the test with -update to create it: %v", path, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s does not match %s, run the test with -update if the change is intended.\ngot:\n%s\nwant:\n%s", name, path, got, want)
+	}
+}
@@ -0,0 +1,385 @@
+/*
+Copyright 2021.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"context"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	cachev1beta1 "github.com/example/memcached-operator/api/v1beta1"
+	"github.com/example/operator-common/owned"
+)
+
+// memcachedFinalizer holds a deleted Memcached until its cleanup has run
+const memcachedFinalizer = "cache.example.com/finalizer"
+
+// memcachedPort is the port memcached listens on
+const memcachedPort = 11211
+
+// defaultMemcachedCleanupTimeout is how long the cleanup may take when Spec.Cleanup.TimeoutSeconds is not set
+const defaultMemcachedCleanupTimeout = time.Minute
+
+// reasonFailedCleanup is the reason of the Event recorded when the pods of a deleted Memcached could not be flushed
+const reasonFailedCleanup = "FailedCleanup"
+
+// MemcachedReconciler reconciles a Memcached object
+type MemcachedReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+
+	// Recorder records Events on Memcached objects, e.g. when the Deployment is created or scaled.
+	// It defaults to a recorder of the manager.
+	Recorder record.EventRecorder
+
+	// APIReader reads the Memcached from the API server rather than the cache before its status is written.
+	// It defaults to the API reader of the manager.
+	APIReader client.Reader
+
+	// objects creates and updates the Deployment of a Memcached
+	objects *owned.Reconciler
+}
+
+// generate rbac to get, list, watch, create, update and patch the memcached status the nencached resource
+// +kubebuilder:rbac:groups=cache.example.com,resources=memcacheds,verbs=get;list;watch;create;update;patch;delete
+
+// generate rbac to get, update and patch the memcached status the memcached/finalizers
+// +kubebuilder:rbac:groups=cache.example.com,resources=memcacheds/status,verbs=get;update;patch
+
+// generate rbac to update the memcached/finalizers
+// +kubebuilder:rbac:groups=cache.example.com,resources=memcacheds/finalizers,verbs=update
+
+// generate rbac to get, list, watch, create, update, patch, and delete deployments
+// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+
+// generate rbac to get,list, and watch pods
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+
+// generate rbac to create and patch events
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+// TODO(user): Modify the Reconcile function to compare the state specified by
+// the Memcached object against the actual cluster state, and then
+// perform operations to make the cluster state reflect the state specified by
+// the user.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.7.0/pkg/reconcile
+func (r *MemcachedReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("memcached", req.NamespacedName)
+
+	// Fetch the Memcached instance
+	memcached := &cachev1beta1.Memcached{}
+	err := r.Get(ctx, req.NamespacedName, memcached)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// Request object not found, could have been deleted after reconcile request.
+			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
+			// Return and don't requeue
+			log.Info("Memcached resource not found. Ignoring since object must be deleted")
+			return ctrl.Result{}, nil
+		}
+		// Error reading the object - requeue the request.
+		log.Error(err, "Failed to get Memcached")
+		return ctrl.Result{}, err
+	}
+
+	// Run the cleanup of a deleted Memcached before releasing it
+	if memcached.DeletionTimestamp != nil {
+		return r.finalizeMemcached(ctx, memcached)
+	}
+	if !controllerutil.ContainsFinalizer(memcached, memcachedFinalizer) {
+		controllerutil.AddFinalizer(memcached, memcachedFinalizer)
+		if err = r.Update(ctx, memcached); err != nil {
+			log.Error(err, "Failed to add finalizer")
+			r.Recorder.Eventf(memcached, corev1.EventTypeWarning, owned.ReasonFailedUpdate, "Failed to add finalizer: %v", err)
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	// Create the deployment if it does not exist, or patch its replicas, image and cache size back to the spec
+	result, err := r.objects.Reconcile(ctx, memcached, owned.Object{
+		Desired: r.deploymentForMemcached(memcached),
+		Merge:   mergeDeployment,
+		Event:   deploymentEvent,
+	})
+	if result != nil {
+		return *result, err
+	}
+
+	// Update the Memcached status with the pod names
+	// List the pods for this memcached's deployment
+	podList := &corev1.PodList{}
+	listOpts := []client.ListOption{
+		client.InNamespace(memcached.Namespace),
+		client.MatchingLabels(labelsForMemcached(memcached.Name)),
+	}
+	if err = r.List(ctx, podList, listOpts...); err != nil {
+		log.Error(err, "Failed to list pods", "Memcached.Namespace", memcached.Namespace, "Memcached.Name", memcached.Name)
+		return ctrl.Result{}, err
+	}
+	podNames := owned.PodNames(podList.Items)
+
+	// Update status.Nodes if needed. The Memcached read from the cache may be stale by now, so the
+	// status is compared with and merge-patched against the latest copy read from the API server.
+	latest, err := r.latestMemcached(ctx, memcached)
+	if err != nil {
+		log.Error(err, "Failed to get Memcached")
+		return ctrl.Result{}, err
+	}
+	if !reflect.DeepEqual(podNames, latest.Status.Nodes) {
+		patch := client.MergeFrom(latest.DeepCopy())
+		latest.Status.Nodes = podNames
+		err := r.Status().Patch(ctx, latest, patch)
+		if err != nil {
+			log.Error(err, "Failed to update Memcached status")
+			r.Recorder.Eventf(memcached, corev1.EventTypeWarning, owned.ReasonFailedUpdateStatus, "Failed to update status: %v", err)
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// deploymentForMemcached returns a memcached Deployment object
+func (r *MemcachedReconciler) deploymentForMemcached(m *cachev1beta1.Memcached) *appsv1.Deployment {
+	ls := labelsForMemcached(m.Name)
+	replicas := m.Spec.Replicas
+	// the defaults are applied here too, for objects created before the defaulting webhook was installed
+	image := m.Spec.Image
+	if image == "" {
+		image = cachev1beta1.DefaultMemcachedImage
+	}
+	cacheSize := m.Spec.CacheSizeMB
+	if cacheSize == 0 {
+		cacheSize = cachev1beta1.DefaultMemcachedCacheSizeMB
+	}
+
+	dep := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      m.Name,
+			Namespace: m.Namespace,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: ls,
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: ls,
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Image:   image,
+						Name:    "memcached",
+						Command: []string{"memcached", fmt.Sprintf("-m=%d", cacheSize), "-o", "modern", "-v"},
+						Ports: []corev1.ContainerPort{{
+							ContainerPort: memcachedPort,
+							Name:          "memcached",
+						}},
+					}},
+				},
+			},
+		},
+	}
+	return dep
+}
+
+// mergeDeployment copies the replicas and the image and command of the memcached container
+// from the desired Deployment into the live one
+func mergeDeployment(liveObj, desiredObj client.Object) {
+	live, desired := liveObj.(*appsv1.Deployment), desiredObj.(*appsv1.Deployment)
+	live.Spec.Replicas = desired.Spec.Replicas
+	container := &live.Spec.Template.Spec.Containers[0]
+	container.Image = desired.Spec.Template.Spec.Containers[0].Image
+	container.Command = desired.Spec.Template.Spec.Containers[0].Command
+}
+
+// deploymentEvent returns the Event recorded when the Deployment was patched: Scaled when only the
+// replicas changed, otherwise Updated for the rollout of a new image or cache size
+func deploymentEvent(liveObj, updatedObj client.Object) (string, string) {
+	live, updated := liveObj.(*appsv1.Deployment), updatedObj.(*appsv1.Deployment)
+	if reflect.DeepEqual(live.Spec.Template, updated.Spec.Template) {
+		return owned.ReasonScaled, fmt.Sprintf("Scaled Deployment %s from %d to %d replicas", updated.Name, *live.Spec.Replicas, *updated.Spec.Replicas)
+	}
+	container := updated.Spec.Template.Spec.Containers[0]
+	return owned.ReasonUpdated, fmt.Sprintf("Rolling out Deployment %s with image %s and command %q", updated.Name, container.Image, strings.Join(container.Command, " "))
+}
+
+// labelsForMemcached returns the labels for selecting the resources
+// belonging to the given memcached CR name.
+func labelsForMemcached(name string) map[string]string {
+	return map[string]string{"app": "memcached", "memcached_cr": name}
+}
+
+// finalizeMemcached runs the cleanup of a deleted Memcached and then removes its finalizer.
+// If the pods cannot be flushed within the timeout the finalizer is removed anyway;
+// the state of the cleanup is recorded in Status.Cleanup.
+func (r *MemcachedReconciler) finalizeMemcached(ctx context.Context, memcached *cachev1beta1.Memcached) (ctrl.Result, error) {
+	log := r.Log.WithValues("memcached", types.NamespacedName{Name: memcached.Name, Namespace: memcached.Namespace})
+	if !controllerutil.ContainsFinalizer(memcached, memcachedFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	if memcached.Spec.Cleanup.Flush {
+		timeout := defaultMemcachedCleanupTimeout
+		if memcached.Spec.Cleanup.TimeoutSeconds != 0 {
+			timeout = time.Duration(memcached.Spec.Cleanup.TimeoutSeconds) * time.Second
+		}
+		failed, err := r.flushMemcached(ctx, memcached)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if len(failed) > 0 {
+			message := "failed to flush " + strings.Join(failed, ", ")
+			if time.Since(memcached.DeletionTimestamp.Time) <= timeout {
+				// Retry until the timeout, reporting the pods that could not be flushed
+				if err = r.setCleanupPhase(ctx, memcached, "Failed", message); err != nil {
+					return ctrl.Result{}, err
+				}
+				return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
+			}
+			log.Info("Cleanup timed out, releasing Memcached", "Message", message)
+			if err = r.setCleanupPhase(ctx, memcached, "TimedOut", message); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+	}
+
+	controllerutil.RemoveFinalizer(memcached, memcachedFinalizer)
+	if err := r.Update(ctx, memcached); err != nil {
+		log.Error(err, "Failed to remove finalizer")
+		r.Recorder.Eventf(memcached, corev1.EventTypeWarning, owned.ReasonFailedUpdate, "Failed to remove finalizer: %v", err)
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// flushMemcached sends flush_all to every running memcached pod and returns the pods that could not be flushed
+func (r *MemcachedReconciler) flushMemcached(ctx context.Context, memcached *cachev1beta1.Memcached) ([]string, error) {
+	podList := &corev1.PodList{}
+	listOpts := []client.ListOption{
+		client.InNamespace(memcached.Namespace),
+		client.MatchingLabels(labelsForMemcached(memcached.Name)),
+	}
+	if err := r.List(ctx, podList, listOpts...); err != nil {
+		r.Log.Error(err, "Failed to list pods", "Memcached.Namespace", memcached.Namespace, "Memcached.Name", memcached.Name)
+		return nil, err
+	}
+	var failed []string
+	for _, pod := range podList.Items {
+		if pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" {
+			continue
+		}
+		if err := flushAll(fmt.Sprintf("%s:%d", pod.Status.PodIP, memcachedPort)); err != nil {
+			r.Log.Info("Failed to flush memcached", "Pod.Name", pod.Name, "Error", err.Error())
+			failed = append(failed, pod.Name)
+		}
+	}
+	return failed, nil
+}
+
+// flushAll invalidates every item of the memcached server at the given address
+func flushAll(address string) error {
+	conn, err := net.DialTimeout("tcp", address, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err = conn.Write([]byte("flush_all\r\n")); err != nil {
+		return err
+	}
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(reply) != "OK" {
+		return fmt.Errorf("unexpected reply %q", strings.TrimSpace(reply))
+	}
+	return nil
+}
+
+// setCleanupPhase records the phase and message of the cleanup of a deleted Memcached
+func (r *MemcachedReconciler) setCleanupPhase(ctx context.Context, memcached *cachev1beta1.Memcached, phase string, message string) error {
+	cleanup := &cachev1beta1.MemcachedCleanupStatus{Phase: phase, Message: message}
+	if memcached.Status.Cleanup != nil && *memcached.Status.Cleanup == *cleanup {
+		return nil
+	}
+	patch := client.MergeFrom(memcached.DeepCopy())
+	memcached.Status.Cleanup = cleanup
+	if err := r.Status().Patch(ctx, memcached, patch); err != nil {
+		r.Log.Error(err, "Failed to update Memcached status")
+		r.Recorder.Eventf(memcached, corev1.EventTypeWarning, owned.ReasonFailedUpdateStatus, "Failed to update status: %v", err)
+		return err
+	}
+	// the failure is recorded once per change of the cleanup, not on every retry
+	r.Recorder.Eventf(memcached, corev1.EventTypeWarning, reasonFailedCleanup, "Cleanup %s: %s", strings.ToLower(phase), message)
+	return nil
+}
+
+// latestMemcached reads the Memcached from the API server, as the copy read from the cache at the
+// start of the reconcile may not hold the status written by the previous reconcile yet
+func (r *MemcachedReconciler) latestMemcached(ctx context.Context, memcached *cachev1beta1.Memcached) (*cachev1beta1.Memcached, error) {
+	reader := r.APIReader
+	if reader == nil {
+		reader = r.Client
+	}
+	latest := &cachev1beta1.Memcached{}
+	err := reader.Get(ctx, types.NamespacedName{Name: memcached.Name, Namespace: memcached.Namespace}, latest)
+	return latest, err
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *MemcachedReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Recorder == nil {
+		r.Recorder = mgr.GetEventRecorderFor("memcached-controller")
+	}
+	if r.APIReader == nil {
+		r.APIReader = mgr.GetAPIReader()
+	}
+	r.objects = &owned.Reconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: r.Recorder,
+		Log:      r.Log,
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&cachev1beta1.Memcached{}).
+		Owns(&appsv1.Deployment{}).
+		Complete(r)
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+
+	cachev1beta1 "github.com/example/memcached-operator/api/v1beta1"
+)
+
+var _ = Describe("Memcached controller", func() {
+	const (
+		timeout  = 10 * time.Second
+		interval = 250 * time.Millisecond
+	)
+
+	var (
+		ctx       context.Context
+		namespace string
+		key       types.NamespacedName
+	)
+
+	// every test runs in its own namespace, envtest has no namespace controller to delete them
+	BeforeEach(func() {
+		ctx = context.Background()
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "memcached-test-"}}
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+		namespace = ns.Name
+		key = types.NamespacedName{Name: "memcached-sample", Namespace: namespace}
+
+		memcached := &cachev1beta1.Memcached{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+			Spec: cachev1beta1.MemcachedSpec{
+				Replicas:    2,
+				Image:       "memcached:1.4.36-alpine",
+				CacheSizeMB: 64,
+			},
+		}
+		Expect(k8sClient.Create(ctx, memcached)).To(Succeed())
+	})
+
+	getDeployment := func() (*appsv1.Deployment, error) {
+		deployment := &appsv1.Deployment{}
+		err := k8sClient.Get(ctx, key, deployment)
+		return deployment, err
+	}
+
+	updateMemcached := func(update func(*cachev1beta1.Memcached)) {
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			memcached := &cachev1beta1.Memcached{}
+			if err := k8sClient.Get(ctx, key, memcached); err != nil {
+				return err
+			}
+			update(memcached)
+			return k8sClient.Update(ctx, memcached)
+		})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	It("creates a Deployment owned by the Memcached", func() {
+		var deployment *appsv1.Deployment
+		Eventually(func() error {
+			var err error
+			deployment, err = getDeployment()
+			return err
+		}, timeout, interval).Should(Succeed())
+
+		Expect(*deployment.Spec.Replicas).To(Equal(int32(2)))
+		Expect(deployment.Spec.Selector.MatchLabels).To(Equal(labelsForMemcached(key.Name)))
+		container := deployment.Spec.Template.Spec.Containers[0]
+		Expect(container.Image).To(Equal("memcached:1.4.36-alpine"))
+		Expect(container.Command).To(ContainElement("-m=64"))
+
+		memcached := &cachev1beta1.Memcached{}
+		Expect(k8sClient.Get(ctx, key, memcached)).To(Succeed())
+		Expect(memcached.Finalizers).To(ContainElement(memcachedFinalizer))
+		owner := metav1.GetControllerOf(deployment)
+		Expect(owner).NotTo(BeNil())
+		Expect(owner.Kind).To(Equal("Memcached"))
+		Expect(owner.UID).To(Equal(memcached.UID))
+	})
+
+	It("scales the Deployment with the replicas of the Memcached", func() {
+		Eventually(getDeployment, timeout, interval).ShouldNot(BeNil())
+		updateMemcached(func(memcached *cachev1beta1.Memcached) {
+			memcached.Spec.Replicas = 4
+		})
+
+		Eventually(func() int32 {
+			deployment, err := getDeployment()
+			if err != nil {
+				return 0
+			}
+			return *deployment.Spec.Replicas
+		}, timeout, interval).Should(Equal(int32(4)))
+	})
+
+	It("lists the memcached pods in status.nodes", func() {
+		Eventually(getDeployment, timeout, interval).ShouldNot(BeNil())
+		// envtest runs no Deployment controller, so the pods are created by the test
+		for _, name := range []string{"memcached-sample-a", "memcached-sample-b"} {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labelsForMemcached(key.Name)},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "memcached", Image: "memcached:1.4.36-alpine"}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+		}
+		// pods are not watched, a change of the Memcached triggers the reconcile recording them
+		updateMemcached(func(memcached *cachev1beta1.Memcached) {
+			memcached.Annotations = map[string]string{"test": "pods-created"}
+		})
+
+		Eventually(func() []string {
+			memcached := &cachev1beta1.Memcached{}
+			if err := k8sClient.Get(ctx, key, memcached); err != nil {
+				return nil
+			}
+			return memcached.Status.Nodes
+		}, timeout, interval).Should(ConsistOf("memcached-sample-a", "memcached-sample-b"))
+	})
+
+	It("releases a deleted Memcached once its cleanup has run", func() {
+		Eventually(func() []string {
+			memcached := &cachev1beta1.Memcached{}
+			if err := k8sClient.Get(ctx, key, memcached); err != nil {
+				return nil
+			}
+			return memcached.Finalizers
+		}, timeout, interval).Should(ContainElement(memcachedFinalizer))
+
+		memcached := &cachev1beta1.Memcached{}
+		Expect(k8sClient.Get(ctx, key, memcached)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, memcached)).To(Succeed())
+
+		Eventually(func() bool {
+			err := k8sClient.Get(ctx, key, &cachev1beta1.Memcached{})
+			return errors.IsNotFound(err)
+		}, timeout, interval).Should(BeTrue())
+	})
+})
@@ -0,0 +1,199 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	cachev1beta1 "github.com/example/memcached-operator/api/v1beta1"
+	"github.com/example/operator-common/owned"
+)
+
+// sampleMemcached returns the Memcached the tests of this file reconcile
+func sampleMemcached() *cachev1beta1.Memcached {
+	return &cachev1beta1.Memcached{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "memcached-sample",
+			Namespace:  "default",
+			Finalizers: []string{memcachedFinalizer},
+		},
+		Spec: cachev1beta1.MemcachedSpec{
+			Replicas:    3,
+			Image:       cachev1beta1.DefaultMemcachedImage,
+			CacheSizeMB: 128,
+		},
+	}
+}
+
+func TestDeploymentForMemcachedGolden(t *testing.T) {
+	r := &MemcachedReconciler{}
+	expectGolden(t, "deployment_for_memcached", r.deploymentForMemcached(sampleMemcached()))
+
+	// the defaults are applied to a Memcached created before the defaulting webhook
+	memcached := sampleMemcached()
+	memcached.Spec.Image = ""
+	memcached.Spec.CacheSizeMB = 0
+	expectGolden(t, "deployment_for_memcached_defaults", r.deploymentForMemcached(memcached))
+}
+
+// errorClient fails the Get, Create or status Patch of a client with the given errors
+type errorClient struct {
+	client.Client
+	getErr    error
+	createErr error
+	statusErr error
+}
+
+func (c *errorClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	if c.getErr != nil {
+		return c.getErr
+	}
+	return c.Client.Get(ctx, key, obj)
+}
+
+func (c *errorClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if c.createErr != nil {
+		return c.createErr
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func (c *errorClient) Status() client.StatusWriter {
+	return &errorStatusWriter{StatusWriter: c.Client.Status(), err: c.statusErr}
+}
+
+// errorStatusWriter fails the status Patch of a client with the given error
+type errorStatusWriter struct {
+	client.StatusWriter
+	err error
+}
+
+func (w *errorStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if w.err != nil {
+		return w.err
+	}
+	return w.StatusWriter.Patch(ctx, obj, patch, opts...)
+}
+
+// newTestMemcachedReconciler returns a MemcachedReconciler on a fake client holding the given objects,
+// with its Get, Create and status Patch failing as set in errs
+func newTestMemcachedReconciler(t *testing.T, errs errorClient, objs ...client.Object) (*MemcachedReconciler, *record.FakeRecorder) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := cachev1beta1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	errs.Client = fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	recorder := record.NewFakeRecorder(10)
+	log := ctrl.Log.WithName("controllers").WithName("Memcached")
+	r := &MemcachedReconciler{Client: &errs, Log: log, Scheme: scheme, Recorder: recorder}
+	r.objects = &owned.Reconciler{Client: &errs, Scheme: scheme, Recorder: recorder, Log: log}
+	return r, recorder
+}
+
+// expectEvent checks that an Event with the given reason was recorded
+func expectEvent(t *testing.T, recorder *record.FakeRecorder, reason string) {
+	t.Helper()
+	for {
+		select {
+		case event := <-recorder.Events:
+			if strings.Contains(event, " "+reason+" ") {
+				return
+			}
+		default:
+			t.Errorf("No %s event was recorded", reason)
+			return
+		}
+	}
+}
+
+var memcachedRequest = ctrl.Request{NamespacedName: types.NamespacedName{Name: "memcached-sample", Namespace: "default"}}
+
+func TestMemcachedReconcileNotFound(t *testing.T) {
+	r, _ := newTestMemcachedReconciler(t, errorClient{})
+	result, err := r.Reconcile(context.Background(), memcachedRequest)
+	if err != nil {
+		t.Fatalf("Reconcile of a deleted Memcached failed: %v", err)
+	}
+	if result != (ctrl.Result{}) {
+		t.Errorf("Reconcile of a deleted Memcached returned %+v, want no requeue", result)
+	}
+}
+
+func TestMemcachedReconcileGetError(t *testing.T) {
+	getErr := fmt.Errorf("connection refused")
+	r, _ := newTestMemcachedReconciler(t, errorClient{getErr: getErr}, sampleMemcached())
+	if _, err := r.Reconcile(context.Background(), memcachedRequest); err != getErr {
+		t.Errorf("Reconcile returned %v, want the Get error %v", err, getErr)
+	}
+}
+
+func TestMemcachedReconcileCreateError(t *testing.T) {
+	createErr := errors.NewForbidden(appsv1.Resource("deployments"), "memcached-sample", fmt.Errorf("quota exceeded"))
+	r, recorder := newTestMemcachedReconciler(t, errorClient{createErr: createErr}, sampleMemcached())
+	if _, err := r.Reconcile(context.Background(), memcachedRequest); err != createErr {
+		t.Errorf("Reconcile returned %v, want the Create error %v", err, createErr)
+	}
+	expectEvent(t, recorder, owned.ReasonFailedCreate)
+}
+
+func TestMemcachedReconcileStatusPatchError(t *testing.T) {
+	memcached := sampleMemcached()
+	deployment := (&MemcachedReconciler{}).deploymentForMemcached(memcached)
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "memcached-sample-0", Namespace: "default", Labels: labelsForMemcached(memcached.Name)},
+	}
+	statusErr := errors.NewConflict(cachev1beta1.GroupVersion.WithResource("memcacheds").GroupResource(), memcached.Name, fmt.Errorf("modified"))
+	r, recorder := newTestMemcachedReconciler(t, errorClient{statusErr: statusErr}, memcached, deployment, pod)
+	if _, err := r.Reconcile(context.Background(), memcachedRequest); err != statusErr {
+		t.Errorf("Reconcile returned %v, want the status Patch error %v", err, statusErr)
+	}
+	expectEvent(t, recorder, owned.ReasonFailedUpdateStatus)
+}
+
+func TestMemcachedReconcileStatusReadFromAPIReader(t *testing.T) {
+	// the cache still holds the pod names, the API server holds a status that was reset since
+	memcached := sampleMemcached()
+	memcached.Status.Nodes = []string{"memcached-sample-0"}
+	deployment := (&MemcachedReconciler{}).deploymentForMemcached(memcached)
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "memcached-sample-0", Namespace: "default", Labels: labelsForMemcached(memcached.Name)},
+	}
+	statusErr := errors.NewConflict(cachev1beta1.GroupVersion.WithResource("memcacheds").GroupResource(), memcached.Name, fmt.Errorf("modified"))
+	r, _ := newTestMemcachedReconciler(t, errorClient{statusErr: statusErr}, memcached, deployment, pod)
+	r.APIReader = fake.NewClientBuilder().WithScheme(r.Scheme).WithObjects(sampleMemcached()).Build()
+	if _, err := r.Reconcile(context.Background(), memcachedRequest); err != statusErr {
+		t.Errorf("Reconcile returned %v, want the status Patch of the API server copy to fail with %v", err, statusErr)
+	}
+}
@@ -0,0 +1,18 @@
+module github.com/example/memcached-operator
+
+go 1.15
+
+require (
+	github.com/example/operator-common v0.0.0
+	github.com/go-logr/logr v0.3.0
+	github.com/onsi/ginkgo v1.14.1
+	github.com/onsi/gomega v1.10.2
+	k8s.io/api v0.19.2
+	k8s.io/apiextensions-apiserver v0.19.2
+	k8s.io/apimachinery v0.19.2
+	k8s.io/client-go v0.19.2
+	sigs.k8s.io/controller-runtime v0.7.2
+	sigs.k8s.io/yaml v1.2.0
+)
+
+replace github.com/example/operator-common => ../operator-common
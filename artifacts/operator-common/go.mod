@@ -0,0 +1,11 @@
+module github.com/example/operator-common
+
+go 1.15
+
+require (
+	github.com/go-logr/logr v0.3.0
+	k8s.io/api v0.19.2
+	k8s.io/apimachinery v0.19.2
+	k8s.io/client-go v0.19.2
+	sigs.k8s.io/controller-runtime v0.7.2
+)
@@ -0,0 +1,32 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package owned
+
+// Reasons of the Events recorded on custom resources by every controller.
+// Normal Events are named after what was done, Warning Events start with Failed.
+const (
+	ReasonCreated      = "Created"
+	ReasonUpdated      = "Updated"
+	ReasonDeleted      = "Deleted"
+	ReasonScaled       = "Scaled"
+	ReasonFailedCreate = "FailedCreate"
+	ReasonFailedUpdate = "FailedUpdate"
+	ReasonFailedDelete = "FailedDelete"
+	ReasonFailedScale  = "FailedScale"
+
+	ReasonFailedUpdateStatus = "FailedUpdateStatus"
+)
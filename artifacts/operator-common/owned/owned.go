@@ -0,0 +1,187 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package owned reconciles the objects owned by a custom resource, such as its Services and
+// workloads, with the same create, update and delete steps for every controller.
+package owned
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// Object is an object owned by the reconciled custom resource
+type Object struct {
+	// Desired is the object as the controller wants it. Its name and namespace identify the live object.
+	Desired client.Object
+
+	// Merge copies the fields the controller manages from desired into live, keeping the fields set by
+	// the cluster or other controllers. The live object is patched when Merge changed it.
+	// Without Merge the object is only created, e.g. when its updates need more care than a patch.
+	Merge func(live, desired client.Object)
+
+	// Event returns the reason and message of the Event recorded when the live object is patched,
+	// given the live object before and after the patch, e.g. Scaled when only the replicas changed.
+	// Defaults to Updated.
+	Event func(live, updated client.Object) (string, string)
+
+	// Absent deletes the object if it exists and is controlled by the custom resource,
+	// e.g. when the feature it belongs to was turned off
+	Absent bool
+
+	// Optional skips the object when its kind is not installed in the cluster,
+	// e.g. a ServiceMonitor without the Prometheus Operator
+	Optional bool
+}
+
+// Reconciler creates, updates and deletes the objects owned by a custom resource and records
+// an Event on the custom resource for every change
+type Reconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	Log      logr.Logger
+}
+
+// Reconcile brings the given objects in line with their desired state, in order.
+// After an object is created it returns a result requeuing the request, so the objects later in the list are
+// only created once the earlier ones exist, e.g. the governing Service before its StatefulSet.
+// Reconcile returns nil, nil once every object matches its desired state.
+func (r *Reconciler) Reconcile(ctx context.Context, owner client.Object, objects ...Object) (*ctrl.Result, error) {
+	for _, object := range objects {
+		if result, err := r.reconcileObject(ctx, owner, object); result != nil {
+			return result, err
+		}
+	}
+	return nil, nil
+}
+
+//reconcileObject creates, patches or deletes a single object
+func (r *Reconciler) reconcileObject(ctx context.Context, owner client.Object, object Object) (*ctrl.Result, error) {
+	desired := object.Desired
+	kind := r.kind(desired)
+	log := r.Log.WithValues(kind+".Namespace", desired.GetNamespace(), kind+".Name", desired.GetName())
+
+	live := newObject(desired)
+	err := r.Get(ctx, client.ObjectKeyFromObject(desired), live)
+	if object.Optional && meta.IsNoMatchError(err) {
+		if !object.Absent {
+			log.Info(kind + " kind is not installed, skipping " + kind)
+		}
+		return nil, nil
+	}
+	if err != nil && errors.IsNotFound(err) {
+		if object.Absent {
+			return nil, nil
+		}
+		if err = controllerutil.SetControllerReference(owner, desired, r.Scheme); err != nil {
+			log.Error(err, "Failed to set owner reference")
+			return &ctrl.Result{}, err
+		}
+		log.Info("Creating a new " + kind)
+		if err = r.Create(ctx, desired); err != nil {
+			log.Error(err, "Failed to create new "+kind)
+			r.Recorder.Eventf(owner, corev1.EventTypeWarning, ReasonFailedCreate, "Failed to create %s %s: %v", kind, desired.GetName(), err)
+			return &ctrl.Result{}, err
+		}
+		r.Recorder.Eventf(owner, corev1.EventTypeNormal, ReasonCreated, "Created %s %s", kind, desired.GetName())
+		// Object created successfully - return and requeue
+		return &ctrl.Result{Requeue: true}, nil
+	} else if err != nil {
+		log.Error(err, "Failed to get "+kind)
+		return &ctrl.Result{}, err
+	}
+
+	if object.Absent {
+		//objects of the same name created by someone else are left alone
+		if !metav1.IsControlledBy(live, owner) {
+			return nil, nil
+		}
+		log.Info("Deleting " + kind)
+		if err = r.Delete(ctx, live); err != nil && !errors.IsNotFound(err) {
+			log.Error(err, "Failed to delete "+kind)
+			r.Recorder.Eventf(owner, corev1.EventTypeWarning, ReasonFailedDelete, "Failed to delete %s %s: %v", kind, desired.GetName(), err)
+			return &ctrl.Result{}, err
+		}
+		r.Recorder.Eventf(owner, corev1.EventTypeNormal, ReasonDeleted, "Deleted %s %s", kind, desired.GetName())
+		return nil, nil
+	}
+
+	if object.Merge == nil {
+		return nil, nil
+	}
+	//compare the desired object against the live one and patch only what changed
+	updated := live.DeepCopyObject().(client.Object)
+	object.Merge(updated, desired)
+	if equality.Semantic.DeepEqual(updated, live) {
+		return nil, nil
+	}
+	log.Info("Updating " + kind)
+	if err = r.Patch(ctx, updated, client.MergeFrom(live)); err != nil {
+		log.Error(err, "Failed to update "+kind)
+		r.Recorder.Eventf(owner, corev1.EventTypeWarning, ReasonFailedUpdate, "Failed to update %s %s: %v", kind, desired.GetName(), err)
+		return &ctrl.Result{}, err
+	}
+	reason, message := ReasonUpdated, "Updated "+kind+" "+desired.GetName()
+	if object.Event != nil {
+		reason, message = object.Event(live, updated)
+	}
+	r.Recorder.Event(owner, corev1.EventTypeNormal, reason, message)
+	return nil, nil
+}
+
+//kind returns the kind of an object, e.g. Service, for logs and Events
+func (r *Reconciler) kind(obj client.Object) string {
+	gvk, err := apiutil.GVKForObject(obj, r.Scheme)
+	if err != nil {
+		return reflect.TypeOf(obj).Elem().Name()
+	}
+	return gvk.Kind
+}
+
+//newObject returns an empty object of the same type as obj to read the live object into.
+//Reading into a copy of the desired object would keep desired map entries the live object does not have.
+func newObject(obj client.Object) client.Object {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		live := &unstructured.Unstructured{}
+		live.SetGroupVersionKind(u.GroupVersionKind())
+		return live
+	}
+	return reflect.New(reflect.TypeOf(obj).Elem()).Interface().(client.Object)
+}
+
+// PodNames returns the names of the given pods
+func PodNames(pods []corev1.Pod) []string {
+	var podNames []string
+	for _, pod := range pods {
+		podNames = append(podNames, pod.Name)
+	}
+	return podNames
+}
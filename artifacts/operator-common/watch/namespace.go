@@ -0,0 +1,68 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package watch configures the namespaces the manager of an operator watches, so an operator
+// can run cluster-wide, or per namespace with a Role instead of a ClusterRole.
+package watch
+
+import (
+	"flag"
+	"os"
+	"strings"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+)
+
+// NamespaceEnvVar is the environment variable holding the comma-separated namespaces to watch
+const NamespaceEnvVar = "WATCH_NAMESPACE"
+
+// BindFlag registers the --watch-namespace flag into value. The flag defaults to NamespaceEnvVar,
+// so the namespaces can be set in the manager Deployment from its own namespace.
+func BindFlag(fs *flag.FlagSet, value *string) {
+	fs.StringVar(value, "watch-namespace", os.Getenv(NamespaceEnvVar),
+		"Comma-separated namespaces to watch. All namespaces are watched when empty.")
+}
+
+// Namespaces returns the namespaces of a comma-separated list, e.g. "team-a, team-b".
+// It returns nil, meaning all namespaces, when the list is empty.
+func Namespaces(value string) []string {
+	var namespaces []string
+	seen := map[string]bool{}
+	for _, namespace := range strings.Split(value, ",") {
+		namespace = strings.TrimSpace(namespace)
+		if namespace == "" || seen[namespace] {
+			continue
+		}
+		seen[namespace] = true
+		namespaces = append(namespaces, namespace)
+	}
+	return namespaces
+}
+
+// Options restricts the cache of the manager to the given namespaces. A single namespace uses the
+// namespaced cache of the manager, several namespaces a cache per namespace. Without namespaces the
+// options are returned unchanged and the manager watches the whole cluster.
+func Options(options ctrl.Options, namespaces []string) ctrl.Options {
+	switch len(namespaces) {
+	case 0:
+	case 1:
+		options.Namespace = namespaces[0]
+	default:
+		options.NewCache = cache.MultiNamespacedCacheBuilder(namespaces)
+	}
+	return options
+}